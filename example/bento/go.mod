@@ -1,6 +1,6 @@
 module github.com/cludden/benthos-plugin-temporal/example/bento
 
-go 1.22.2
+go 1.24.0
 
 replace github.com/cludden/benthos-plugin-temporal/pkg/bento v0.0.0 => ../../pkg/bento
 
@@ -8,7 +8,7 @@ require (
 	github.com/cludden/benthos-plugin-temporal/pkg/bento v0.0.0
 	github.com/oklog/run v1.1.0
 	github.com/warpstreamlabs/bento v1.0.2
-	go.temporal.io/sdk v1.27.0
+	go.temporal.io/sdk v1.40.0
 )
 
 require (
@@ -309,7 +309,7 @@ require (
 	go.opentelemetry.io/otel/sdk v1.24.0 // indirect
 	go.opentelemetry.io/otel/trace v1.24.0 // indirect
 	go.opentelemetry.io/proto/otlp v1.1.0 // indirect
-	go.temporal.io/api v1.34.0 // indirect
+	go.temporal.io/api v1.62.12 // indirect
 	go.uber.org/atomic v1.11.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
@@ -1,6 +1,6 @@
 module github.com/cludden/benthos-plugin-temporal/example/connect
 
-go 1.22.2
+go 1.24.0
 
 replace github.com/cludden/benthos-plugin-temporal/pkg/connect v0.0.0 => ../../pkg/connect
 
@@ -9,7 +9,7 @@ require (
 	github.com/oklog/run v1.1.0
 	github.com/redpanda-data/benthos/v4 v4.30.0
 	github.com/redpanda-data/connect/public/bundle/free/v4 v4.29.0
-	go.temporal.io/sdk v1.27.0
+	go.temporal.io/sdk v1.40.0
 )
 
 require (
@@ -304,7 +304,7 @@ require (
 	go.opentelemetry.io/otel/sdk v1.24.0 // indirect
 	go.opentelemetry.io/otel/trace v1.24.0 // indirect
 	go.opentelemetry.io/proto/otlp v1.1.0 // indirect
-	go.temporal.io/api v1.34.0 // indirect
+	go.temporal.io/api v1.62.12 // indirect
 	go.uber.org/atomic v1.11.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	go.uber.org/zap v1.27.0 // indirect
@@ -1,6 +1,12 @@
 package all
 
 import (
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/client_resource"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/lifecycle_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/query_processor"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/signal_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/update_output"
 	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/verify_hmac_sha256_processor"
 	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/workflow_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/bento/workflow_processor"
 )
@@ -1,5 +1,5 @@
 module github.com/cludden/benthos-plugin-temporal/pkg/bento
 
-go 1.22.2
+go 1.24.0
 
 require github.com/warpstreamlabs/bento v1.0.2 // indirect
@@ -9,8 +9,8 @@ import (
 )
 
 func init() {
-	if err := service.RegisterOutput(plugin.WorkflowOutputType, plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), bento.DefaultFieldProvider), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
-		return plugin.NewWorkflowOutput(conf, mgr)
+	if err := service.RegisterBatchOutput(plugin.WorkflowOutputType, plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), bento.DefaultFieldProvider), func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchOutput, service.BatchPolicy, int, error) {
+		return plugin.NewWorkflowOutput(conf, mgr, bento.BatchError)
 	}); err != nil {
 		panic(fmt.Errorf("error registering %s output: %w", plugin.WorkflowOutputType, err))
 	}
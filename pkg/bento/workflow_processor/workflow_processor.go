@@ -0,0 +1,17 @@
+package workflowprocessor
+
+import (
+	"fmt"
+
+	"github.com/cludden/benthos-plugin-temporal/pkg/bento"
+	"github.com/cludden/benthos-plugin-temporal/pkg/plugin"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func init() {
+	if err := service.RegisterProcessor(plugin.WorkflowProcessorType, plugin.NewWorkflowProcessorConfig(service.NewConfigSpec(), bento.DefaultFieldProvider), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return plugin.NewWorkflowProcessor(conf, mgr, bento.MessageBatch)
+	}); err != nil {
+		panic(fmt.Errorf("error registering %s processor: %w", plugin.WorkflowProcessorType, err))
+	}
+}
@@ -1,6 +1,12 @@
 package all
 
 import (
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/client_resource"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/lifecycle_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/query_processor"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/signal_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/update_output"
 	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/verify_hmac_sha256_processor"
 	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/workflow_output"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/workflow_processor"
 )
@@ -0,0 +1,17 @@
+package clientresource
+
+import (
+	"fmt"
+
+	"github.com/cludden/benthos-plugin-temporal/pkg/connect"
+	"github.com/cludden/benthos-plugin-temporal/pkg/plugin"
+	"github.com/redpanda-data/benthos/v4/public/service"
+)
+
+func init() {
+	if err := service.RegisterCache(plugin.ClientResourceType, plugin.NewClientResourceConfig(service.NewConfigSpec(), connect.DefaultFieldProvider), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+		return plugin.NewClientResource(conf, mgr)
+	}); err != nil {
+		panic(fmt.Errorf("error registering %s cache: %w", plugin.ClientResourceType, err))
+	}
+}
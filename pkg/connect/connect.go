@@ -10,6 +10,10 @@ var (
 
 type FieldProvider struct{}
 
+func (fp *FieldProvider) NewBatchPolicyField(name string) *service.ConfigField {
+	return service.NewBatchPolicyField(name)
+}
+
 func (fp *FieldProvider) NewBloblangField(name string) *service.ConfigField {
 	return service.NewBloblangField(name)
 }
@@ -18,6 +22,14 @@ func (fp *FieldProvider) NewBoolField(name string) *service.ConfigField {
 	return service.NewBoolField(name)
 }
 
+func (fp *FieldProvider) NewDurationField(name string) *service.ConfigField {
+	return service.NewDurationField(name)
+}
+
+func (fp *FieldProvider) NewFloatField(name string) *service.ConfigField {
+	return service.NewFloatField(name)
+}
+
 func (fp *FieldProvider) NewInterpolatedStringEnumField(name string, values ...string) *service.ConfigField {
 	return service.NewInterpolatedStringEnumField(name, values...)
 }
@@ -34,10 +46,41 @@ func (fp *FieldProvider) NewObjectField(name string, fields ...*service.ConfigFi
 	return service.NewObjectField(name, fields...)
 }
 
+func (fp *FieldProvider) NewStringEnumField(name string, values ...string) *service.ConfigField {
+	return service.NewStringEnumField(name, values...)
+}
+
 func (fp *FieldProvider) NewStringField(name string) *service.ConfigField {
 	return service.NewStringField(name)
 }
 
+func (fp *FieldProvider) NewStringListField(name string) *service.ConfigField {
+	return service.NewStringListField(name)
+}
+
+func (fp *FieldProvider) NewStringMapField(name string) *service.ConfigField {
+	return service.NewStringMapField(name)
+}
+
+// BatchError returns a batch error identifying the messages in batch that
+// failed, where errs is indexed by message, or nil if no message failed.
+func BatchError(batch service.MessageBatch, errs []error) error {
+	var batchErr *service.BatchError
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+	if batchErr == nil {
+		return nil
+	}
+	return batchErr
+}
+
 func MessageBatch(msgs []*service.Message) service.MessageBatch {
 	return service.MessageBatch(msgs)
 }
@@ -1,35 +1,109 @@
-package connect
+package connect_test
 
 import (
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
 	"fmt"
 	"log/slog"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/cludden/benthos-plugin-temporal/pkg/connect"
+	_ "github.com/cludden/benthos-plugin-temporal/pkg/connect/all"
+	"github.com/cludden/benthos-plugin-temporal/pkg/plugin"
+	"github.com/google/uuid"
+	"github.com/redpanda-data/benthos/v4/public/bloblang"
+	_ "github.com/redpanda-data/benthos/v4/public/components/pure"
 	"github.com/redpanda-data/benthos/v4/public/service"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/api/enums/v1"
 	"go.temporal.io/api/filter/v1"
+	"go.temporal.io/api/operatorservice/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/opentelemetry"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/testsuite"
 	"go.temporal.io/sdk/worker"
 	"go.temporal.io/sdk/workflow"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
+// srv is a Temporal dev server shared by the integration tests
+var srv *testsuite.DevServer
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if testing.Short() {
+		os.Exit(m.Run())
+	}
+
+	var err error
+	srv, err = testsuite.StartDevServer(context.Background(), testsuite.DevServerOptions{
+		ExtraArgs: []string{
+			"--dynamic-config-value", "frontend.enableExecuteMultiOperation=true",
+			"--namespace", "tenant",
+			"--search-attribute", "CustomerId=Keyword",
+			"--search-attribute", "Amount=Double",
+			"--search-attribute", "ItemCount=Int",
+			"--search-attribute", "OrderedAt=Datetime",
+			"--search-attribute", "Tags=KeywordList",
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error starting dev server: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := srv.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "error stopping dev server: %v\n", err)
+	}
+	os.Exit(code)
+}
+
 func TestConnectWorkflowOutput_Basic(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
 	}
 	r, ctx := require.New(t), context.Background()
 
-	srv, err := testsuite.StartDevServer(ctx, testsuite.DevServerOptions{})
+	// dial a dedicated client, as the dev server's client is shared by the
+	// other tests
+	c, err := client.Dial(client.Options{HostPort: srv.FrontendHostPort()})
 	r.NoError(err)
-	t.Cleanup(func() {
-		r.NoError(srv.Stop())
-	})
-
-	c := srv.Client()
 	t.Cleanup(c.Close)
 
 	w := worker.New(c, "test", worker.Options{})
@@ -39,6 +113,7 @@ func TestConnectWorkflowOutput_Basic(t *testing.T) {
 	r.NoError(w.Start())
 	t.Cleanup(w.Stop)
 
+	id := "basic/" + uuid.NewString()
 	builder := service.NewStreamBuilder()
 	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
 	producer, err := builder.AddProducerFunc()
@@ -47,9 +122,9 @@ func TestConnectWorkflowOutput_Basic(t *testing.T) {
 temporal_workflow:
   address: %s
   task_queue: test
-  workflow_id: basic/${! uuid_v4() }
+  workflow_id: %s
   workflow_type: basic
-`, srv.FrontendHostPort())))
+`, srv.FrontendHostPort(), id)))
 	stream, err := builder.Build()
 	r.NoError(err)
 
@@ -64,16 +139,19 @@ temporal_workflow:
 	r.NoError(stream.Stop(ctx))
 	g.Wait()
 
-	workflows, err := c.WorkflowService().ListClosedWorkflowExecutions(ctx, &workflowservice.ListClosedWorkflowExecutionsRequest{
-		Namespace: "default",
-		Filters: &workflowservice.ListClosedWorkflowExecutionsRequest_TypeFilter{
-			TypeFilter: &filter.WorkflowTypeFilter{
-				Name: "basic",
+	// closed executions are visible to list requests asynchronously
+	var workflows *workflowservice.ListClosedWorkflowExecutionsResponse
+	r.Eventually(func() bool {
+		workflows, err = c.WorkflowService().ListClosedWorkflowExecutions(ctx, &workflowservice.ListClosedWorkflowExecutionsRequest{
+			Namespace: "default",
+			Filters: &workflowservice.ListClosedWorkflowExecutionsRequest_ExecutionFilter{
+				ExecutionFilter: &filter.WorkflowExecutionFilter{
+					WorkflowId: id,
+				},
 			},
-		},
-	})
-	r.NoError(err)
-	r.NotNil(workflows)
+		})
+		return err == nil && len(workflows.GetExecutions()) > 0
+	}, 10*time.Second, 100*time.Millisecond)
 	r.Len(workflows.Executions, 1)
 	exec := workflows.GetExecutions()[0].GetExecution()
 
@@ -81,3 +159,2337 @@ temporal_workflow:
 	r.NoError(c.GetWorkflow(ctx, exec.GetWorkflowId(), exec.GetRunId()).Get(ctx, &result))
 	r.Equal(map[string]any{"foo": "bar"}, result)
 }
+
+func TestConnectSignalOutput_Basic(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) (map[string]any, error) {
+		var input map[string]any
+		workflow.GetSignalChannel(ctx, "foo").Receive(ctx, &input)
+		return input, nil
+	}, workflow.RegisterOptions{Name: "signaled"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        "signaled/basic",
+		TaskQueue: "test",
+	}, "signaled")
+	r.NoError(err)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_signal:
+  address: %s
+  workflow_id: ${! @.workflow_id }
+  signal_name: foo
+  args: 'root = this.merge({"signaled": true})'
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	msg := service.NewMessage([]byte(`{"foo":"bar"}`))
+	msg.MetaSetMut("workflow_id", run.GetID())
+	r.NoError(producer(ctx, msg))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	result := make(map[string]any)
+	r.NoError(run.Get(ctx, &result))
+	r.Equal(map[string]any{"foo": "bar", "signaled": true}, result)
+}
+
+func TestConnectWorkflowOutput_SignalWithStart(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) ([]any, error) {
+		events := []any{input}
+		ch := workflow.GetSignalChannel(ctx, "event")
+		for len(events) < 3 {
+			var event map[string]any
+			ch.Receive(ctx, &event)
+			events = append(events, event)
+		}
+		return events, nil
+	}, workflow.RegisterOptions{Name: "entity"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  detach: "true"
+  mapping: 'root.entity = this.entity'
+  task_queue: test
+  workflow_id: entity/${! this.entity }
+  workflow_type: entity
+  signal:
+    name: event
+    args: 'root.seq = this.seq'
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"entity":"foo","seq":1}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"entity":"foo","seq":2}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	var result []any
+	r.NoError(c.GetWorkflow(ctx, "entity/foo", "").Get(ctx, &result))
+	r.Equal([]any{
+		map[string]any{"entity": "foo"},
+		map[string]any{"seq": float64(1)},
+		map[string]any{"seq": float64(2)},
+	}, result)
+}
+
+func TestConnectUpdateOutput_UpdateWithStart(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (float64, error) {
+		var total float64
+		if err := workflow.SetUpdateHandler(ctx, "add", func(ctx workflow.Context, n float64) (float64, error) {
+			total += n
+			return total, nil
+		}); err != nil {
+			return 0, err
+		}
+		if err := workflow.Await(ctx, func() bool {
+			return total >= 4 && workflow.AllHandlersFinished(ctx)
+		}); err != nil {
+			return 0, err
+		}
+		return total, nil
+	}, workflow.RegisterOptions{Name: "counter"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_update:
+  address: %s
+  args: root = this.n
+  max_in_flight: 1
+  update_id: ${! this.id }
+  update_name: add
+  workflow_id: counter/${! this.counter }
+  start:
+    mapping: root = {}
+    task_queue: test
+    workflow_type: counter
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"counter":"foo","id":"a","n":1}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"counter":"foo","id":"a","n":1}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"counter":"foo","id":"b","n":3}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	var result float64
+	r.NoError(c.GetWorkflow(ctx, "counter/foo", "").Get(ctx, &result))
+	r.Equal(float64(4), result)
+}
+
+func TestConnectWorkflowProcessor_ResultMapping(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return map[string]any{"greeting": fmt.Sprintf("hello %s", input["name"])}, nil
+	}, workflow.RegisterOptions{Name: "greet"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddProcessorYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  mapping: 'root.name = this.name'
+  result_mapping: 'root.greeting = this.greeting'
+  task_queue: test
+  workflow_id: greet/${! this.name }
+  workflow_type: greet
+`, srv.FrontendHostPort())))
+
+	var mu sync.Mutex
+	var results []*service.Message
+	r.NoError(builder.AddConsumerFunc(func(ctx context.Context, msg *service.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, msg)
+		return nil
+	}))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"id":1,"name":"world"}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	r.Len(results, 1)
+	r.NoError(results[0].GetError())
+	b, err := results[0].AsBytes()
+	r.NoError(err)
+	r.JSONEq(`{"id":1,"name":"world","greeting":"hello world"}`, string(b))
+	workflowID, _ := results[0].MetaGet("workflow_id")
+	r.Equal("greet/world", workflowID)
+	runID, _ := results[0].MetaGet("run_id")
+	r.NotEmpty(runID)
+}
+
+func TestConnectQueryProcessor_Basic(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) error {
+		items := []string{"foo", "bar"}
+		if err := workflow.SetQueryHandler(ctx, "items", func(limit int) ([]string, error) {
+			return items[:limit], nil
+		}); err != nil {
+			return err
+		}
+		return workflow.Await(ctx, func() bool { return false })
+	}, workflow.RegisterOptions{Name: "cart"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        "cart/query",
+		TaskQueue: "test",
+	}, "cart")
+	r.NoError(err)
+	t.Cleanup(func() {
+		_ = c.TerminateWorkflow(ctx, run.GetID(), run.GetRunID(), "test complete")
+	})
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddProcessorYAML(fmt.Sprintf(`
+temporal_query:
+  address: %s
+  args: root = this.limit
+  query_type: ${! this.query }
+  result_mapping: root.items = this
+  workflow_id: ${! this.cart }
+`, srv.FrontendHostPort())))
+
+	var mu sync.Mutex
+	var results []*service.Message
+	r.NoError(builder.AddConsumerFunc(func(ctx context.Context, msg *service.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, msg)
+		return nil
+	}))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"cart":"cart/query","query":"items","limit":1}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"cart":"cart/query","query":"unknown","limit":1}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	r.Len(results, 2)
+	r.NoError(results[0].GetError())
+	b, err := results[0].AsBytes()
+	r.NoError(err)
+	r.JSONEq(`{"cart":"cart/query","query":"items","limit":1,"items":["foo"]}`, string(b))
+	r.ErrorContains(results[1].GetError(), "query unknown failed")
+}
+
+func TestConnectLifecycleOutput_Basic(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) error {
+		return workflow.Await(ctx, func() bool { return false })
+	}, workflow.RegisterOptions{Name: "order"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	var runs []client.WorkflowRun
+	for _, id := range []string{"order/1", "order/2"} {
+		run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+			ID:        id,
+			TaskQueue: "test",
+		}, "order")
+		r.NoError(err)
+		runs = append(runs, run)
+	}
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+switch:
+  cases:
+    - check: this.operation == "cancel"
+      output:
+        temporal_lifecycle:
+          address: %[1]s
+          operation: cancel
+          workflow_id: order/${! this.order }
+    - output:
+        temporal_lifecycle:
+          address: %[1]s
+          operation: terminate
+          reason: ${! this.reason }
+          workflow_id: order/${! this.order }
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"order":1,"operation":"cancel","reason":"aborted"}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"order":2,"operation":"terminate","reason":"aborted"}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"order":3,"operation":"terminate","reason":"aborted"}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	r.Error(runs[0].Get(ctx, nil))
+	desc, err := c.DescribeWorkflowExecution(ctx, runs[0].GetID(), runs[0].GetRunID())
+	r.NoError(err)
+	r.Equal(enums.WORKFLOW_EXECUTION_STATUS_CANCELED, desc.GetWorkflowExecutionInfo().GetStatus())
+
+	r.Error(runs[1].Get(ctx, nil))
+	desc, err = c.DescribeWorkflowExecution(ctx, runs[1].GetID(), runs[1].GetRunID())
+	r.NoError(err)
+	r.Equal(enums.WORKFLOW_EXECUTION_STATUS_TERMINATED, desc.GetWorkflowExecutionInfo().GetStatus())
+}
+
+func TestConnectWorkflowOutput_StartOptions(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	id := "delayed/" + uuid.NewString()
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  task_queue: test
+  workflow_id: %s
+  workflow_type: delayed
+  detach: "true"
+  execution_timeout: ${! @.timeout }
+  run_timeout: 30m
+  task_timeout: 15s
+  start_delay: 1h
+  workflow_id_reuse_policy: reject_duplicate
+  retry_policy:
+    initial_interval: 2s
+    backoff_coefficient: 3
+    maximum_interval: 1m
+    maximum_attempts: 4
+    non_retryable_error_types: [InvalidArgument]
+`, srv.FrontendHostPort(), id)))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	msg := service.NewMessage([]byte(`{"foo":"bar"}`))
+	msg.MetaSetMut("timeout", "2h")
+	r.NoError(producer(ctx, msg))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	desc, err := c.DescribeWorkflowExecution(ctx, id, "")
+	r.NoError(err)
+	r.Equal(2*time.Hour, desc.GetExecutionConfig().GetWorkflowExecutionTimeout().AsDuration())
+	r.Equal(30*time.Minute, desc.GetExecutionConfig().GetWorkflowRunTimeout().AsDuration())
+	r.Equal(15*time.Second, desc.GetExecutionConfig().GetDefaultWorkflowTaskTimeout().AsDuration())
+	info := desc.GetWorkflowExecutionInfo()
+	r.Equal(time.Hour, info.GetExecutionTime().AsTime().Sub(info.GetStartTime().AsTime()))
+	r.NoError(c.TerminateWorkflow(ctx, id, "", "test complete"))
+}
+
+func TestConnectWorkflowOutput_StartOptionsValidation(t *testing.T) {
+	for name, c := range map[string]struct {
+		conf string
+		err  string
+	}{
+		"defaults": {},
+		"cron with start delay": {
+			conf: `
+cron_schedule: "@hourly"
+start_delay: 5m`,
+			err: "cannot specify both cron_schedule and start_delay",
+		},
+		"backoff coefficient": {
+			conf: `
+retry_policy:
+  backoff_coefficient: 0.5`,
+			err: "retry_policy.backoff_coefficient",
+		},
+		"maximum interval": {
+			conf: `
+retry_policy:
+  initial_interval: 1m
+  maximum_interval: 1s`,
+			err: "retry_policy.maximum_interval",
+		},
+		"args with mapping": {
+			conf: `
+args: root = [this]
+mapping: root = this`,
+			err: "cannot specify args with mapping",
+		},
+		"args proto message names without args": {
+			conf: `
+args_proto_message_names: [example.v1.Input]`,
+			err: "args_proto_message_names requires args",
+		},
+		"args proto message names without scheme": {
+			conf: `
+args: root = [this]
+args_proto_message_names: [example.v1.Input]`,
+			err: "args_proto_message_names requires a protobuf scheme",
+		},
+		"invalid search attribute type": {
+			conf: `
+search_attribute_types:
+  CustomerId: uuid`,
+			err: "invalid search_attribute_types.CustomerId",
+		},
+		"client resource with address": {
+			conf: `
+client_resource: shared`,
+			err: "cannot specify address, auth, codec_auth, codec_endpoint, codecs, encryption, env_config, namespace or tls with client_resource",
+		},
+		"client resource with interpolated namespace": {
+			conf: `
+client_resource: shared
+namespace: ${! meta("namespace") }`,
+			err: "cannot specify an interpolated address or namespace with client_resource",
+		},
+		"client pool max clients": {
+			conf: `
+namespace: ${! meta("namespace") }
+client_pool:
+  max_clients: 0`,
+			err: "client_pool.max_clients must be at least 1",
+		},
+		"batch max in flight": {
+			conf: `
+batch_max_in_flight: 0`,
+			err: "batch_max_in_flight must be at least 1",
+		},
+		"api key and api key file": {
+			conf: `
+auth:
+  api_key: secret
+  api_key_file: ./api_key`,
+			err: "cannot specify both auth.api_key and auth.api_key_file",
+		},
+		"missing api key file": {
+			conf: `
+auth:
+  api_key_file: ./missing`,
+			err: "error reading auth.api_key_file",
+		},
+		"encryption key not found": {
+			conf: `
+encryption:
+  key_id: key-2
+  keys:
+    key-1: 0000000000000000000000000000000000000000000000000000000000000000`,
+			err: "encryption key key-2 not found",
+		},
+		"invalid encryption key": {
+			conf: `
+encryption:
+  key_id: key-1
+  keys:
+    key-1: too-short`,
+			err: "invalid encryption key key-1",
+		},
+		"encryption keys without key id": {
+			conf: `
+encryption:
+  keys:
+    key-1: 0000000000000000000000000000000000000000000000000000000000000000`,
+			err: "encryption.key_id is required",
+		},
+		"invalid codec": {
+			conf: `
+codecs: [gzip]`,
+			err: "invalid codecs[0]: gzip",
+		},
+		"duplicate codec": {
+			conf: `
+codecs: [zlib, zlib]`,
+			err: "duplicate codecs[1]: zlib",
+		},
+		"remote codec without endpoint": {
+			conf: `
+codecs: [remote]`,
+			err: "remote codec requires codec_endpoint",
+		},
+		"encryption not listed in codecs": {
+			conf: `
+codecs: [zlib]
+encryption:
+  key_id: key-1
+  keys:
+    key-1: 0000000000000000000000000000000000000000000000000000000000000000`,
+			err: "encryption codec is configured but not listed in codecs",
+		},
+		"missing env config file": {
+			conf: `
+env_config:
+  config_file: ./missing.toml`,
+			err: "error loading env_config",
+		},
+		"invalid propagate metadata pattern": {
+			conf: `
+propagate_metadata:
+  include_patterns: ["("]`,
+			err: "invalid propagate_metadata.include_patterns[0]",
+		},
+		"terminate if running with conflict policy": {
+			conf: `
+workflow_id_conflict_policy: fail
+workflow_id_reuse_policy: terminate_if_running`,
+			err: "cannot specify both workflow_id_conflict_policy",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+			parsed, err := spec.ParseYAML(`
+address: localhost:7233
+task_queue: test
+workflow_id: test
+workflow_type: test
+`+c.conf, nil)
+			require.NoError(t, err)
+			_, _, _, err = plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+			if c.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, c.err)
+		})
+	}
+}
+
+func TestConnectWorkflowOutput_MemoAndHeaders(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	id := "annotated/" + uuid.NewString()
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  task_queue: test
+  workflow_id: %s
+  workflow_type: annotated
+  detach: "true"
+  headers: 'root.tenant = @tenant'
+  memo: 'root.source = this.source'
+  mapping: 'root = this.without("source")'
+`, srv.FrontendHostPort(), id)))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	msg := service.NewMessage([]byte(`{"foo":"bar","source":"test"}`))
+	msg.MetaSetMut("tenant", "acme")
+	r.NoError(producer(ctx, msg))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	desc, err := c.DescribeWorkflowExecution(ctx, id, "")
+	r.NoError(err)
+	var source string
+	r.NoError(converter.GetDefaultDataConverter().FromPayload(desc.GetWorkflowExecutionInfo().GetMemo().GetFields()["source"], &source))
+	r.Equal("test", source)
+
+	iter := c.GetWorkflowHistory(ctx, id, "", false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	r.True(iter.HasNext())
+	event, err := iter.Next()
+	r.NoError(err)
+	started := event.GetWorkflowExecutionStartedEventAttributes()
+	r.NotNil(started)
+	var tenant string
+	r.NoError(converter.GetDefaultDataConverter().FromPayload(started.GetHeader().GetFields()["tenant"], &tenant))
+	r.Equal("acme", tenant)
+	var input map[string]any
+	r.NoError(converter.GetDefaultDataConverter().FromPayloads(started.GetInput(), &input))
+	r.Equal(map[string]any{"foo": "bar"}, input)
+	r.NoError(c.TerminateWorkflow(ctx, id, "", "test complete"))
+}
+
+func TestConnectWorkflowOutput_TypedSearchAttributes(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	// the dev server registers search attributes asynchronously after startup
+	r.Eventually(func() bool {
+		sas, err := c.OperatorService().ListSearchAttributes(ctx, &operatorservice.ListSearchAttributesRequest{Namespace: "default"})
+		return err == nil && len(sas.GetCustomAttributes()) == 5
+	}, 10*time.Second, 100*time.Millisecond)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  task_queue: test
+  workflow_id: order/${! this.id }
+  workflow_type: order
+  detach: "true"
+  search_attribute_types:
+    CustomerId: keyword
+    Amount: double
+    ItemCount: int
+    OrderedAt: datetime
+    Tags: keyword_list
+  search_attributes: |
+    root.CustomerId = this.customer
+    root.Amount = this.amount
+    root.ItemCount = this.items.length().string()
+    root.OrderedAt = this.ordered_at
+    root.Tags = this.tags
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	id := uuid.NewString()
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"id":"`+id+`","customer":"c1","amount":12.5,"items":["a","b","c"],"ordered_at":"2024-01-02T03:04:05Z","tags":["new","priority"]}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	desc, err := c.DescribeWorkflowExecution(ctx, "order/"+id, "")
+	r.NoError(err)
+	fields := desc.GetWorkflowExecutionInfo().GetSearchAttributes().GetIndexedFields()
+	dc := converter.GetDefaultDataConverter()
+
+	var customer string
+	r.NoError(dc.FromPayload(fields["CustomerId"], &customer))
+	r.Equal("c1", customer)
+	var amount float64
+	r.NoError(dc.FromPayload(fields["Amount"], &amount))
+	r.Equal(12.5, amount)
+	var count int64
+	r.NoError(dc.FromPayload(fields["ItemCount"], &count))
+	r.Equal(int64(3), count)
+	var orderedAt time.Time
+	r.NoError(dc.FromPayload(fields["OrderedAt"], &orderedAt))
+	r.True(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).Equal(orderedAt))
+	var tags []string
+	r.NoError(dc.FromPayload(fields["Tags"], &tags))
+	r.Equal([]string{"new", "priority"}, tags)
+	r.NoError(c.TerminateWorkflow(ctx, "order/"+id, "", "test complete"))
+}
+
+func TestConnectWorkflowOutput_PositionalArgs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, name string, count int, opts map[string]any) (map[string]any, error) {
+		return map[string]any{"name": name, "count": count, "opts": opts}, nil
+	}, workflow.RegisterOptions{Name: "positional"})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) (string, error) {
+		return "none", nil
+	}, workflow.RegisterOptions{Name: "noargs"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  task_queue: test
+  workflow_id: ${! this.type }/basic
+  workflow_type: ${! this.type }
+  args: 'root = if this.type == "positional" { [this.name, this.count, this.opts] } else { [] }'
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"type":"positional","name":"foo","count":3,"opts":{"bar":true}}`))))
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"type":"noargs"}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	result := make(map[string]any)
+	r.NoError(c.GetWorkflow(ctx, "positional/basic", "").Get(ctx, &result))
+	r.Equal(map[string]any{"name": "foo", "count": float64(3), "opts": map[string]any{"bar": true}}, result)
+
+	var none string
+	r.NoError(c.GetWorkflow(ctx, "noargs/basic", "").Get(ctx, &none))
+	r.Equal("none", none)
+}
+
+func TestConnectWorkflowOutput_BatchPartialFailure(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: batch/${! this.id.not_null() }
+workflow_type: batch
+detach: "true"
+batch_max_in_flight: 2
+batching:
+  count: 3
+`, srv.FrontendHostPort()), nil)
+	r.NoError(err)
+	out, policy, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+	r.NoError(err)
+	r.Equal(3, policy.Count)
+	r.NoError(out.Connect(ctx))
+	t.Cleanup(func() {
+		r.NoError(out.Close(ctx))
+	})
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{}`)),
+		service.NewMessage([]byte(`{"id":"c"}`)),
+	}
+	err = out.WriteBatch(ctx, batch)
+	var batchErr *service.BatchError
+	r.ErrorAs(err, &batchErr)
+	r.Equal(1, batchErr.IndexedErrors())
+	var failed []int
+	batchErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	r.Equal([]int{1}, failed)
+
+	for _, id := range []string{"batch/a", "batch/c"} {
+		_, err := c.DescribeWorkflowExecution(ctx, id, "")
+		r.NoError(err)
+		r.NoError(c.TerminateWorkflow(ctx, id, "", "test complete"))
+	}
+}
+
+func TestConnectWorkflowOutput_Aggregate(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return input, nil
+	}, workflow.RegisterOptions{Name: "aggregate"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddBatchProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  task_queue: test
+  workflow_id: aggregate/${! @.import_id }
+  workflow_type: aggregate
+  aggregate: true
+  batching:
+    count: 3
+  mapping: 'root = {"count": this.length(), "rows": this}'
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	var batch service.MessageBatch
+	for i := 0; i < 3; i++ {
+		msg := service.NewMessage([]byte(fmt.Sprintf(`{"n":%d}`, i)))
+		msg.MetaSetMut("import_id", "foo")
+		batch = append(batch, msg)
+	}
+	r.NoError(producer(ctx, batch))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	result := make(map[string]any)
+	r.NoError(c.GetWorkflow(ctx, "aggregate/foo", "").Get(ctx, &result))
+	r.Equal(map[string]any{
+		"count": float64(3),
+		"rows":  []any{map[string]any{"n": float64(0)}, map[string]any{"n": float64(1)}, map[string]any{"n": float64(2)}},
+	}, result)
+
+	// a failure is reported for every message in the batch
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: aggregate/${! @.import_id.not_null() }
+workflow_type: aggregate
+aggregate: true
+`, srv.FrontendHostPort()), nil)
+	r.NoError(err)
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+	r.NoError(err)
+	r.NoError(out.Connect(ctx))
+	t.Cleanup(func() {
+		r.NoError(out.Close(ctx))
+	})
+	err = out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"n":0}`)),
+		service.NewMessage([]byte(`{"n":1}`)),
+	})
+	var batchErr *service.BatchError
+	r.ErrorAs(err, &batchErr)
+	r.Equal(2, batchErr.IndexedErrors())
+}
+
+func TestConnectWorkflowOutput_OnAlreadyStarted(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (string, error) {
+		var done string
+		workflow.GetSignalChannel(ctx, "done").Receive(ctx, &done)
+		return done, nil
+	}, workflow.RegisterOptions{Name: "long_running"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	run, err := c.ExecuteWorkflow(ctx, client.StartWorkflowOptions{
+		ID:        "long_running/dupe",
+		TaskQueue: "test",
+	}, "long_running", map[string]any{})
+	r.NoError(err)
+
+	newOutput := func(onAlreadyStarted string) *plugin.WorkflowOutput[*service.InterpolatedString, *bloblang.Executor, *service.Message, service.MessageBatch] {
+		spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+		parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: long_running/dupe
+workflow_type: long_running
+on_already_started: %s
+`, srv.FrontendHostPort(), onAlreadyStarted), nil)
+		r.NoError(err)
+		out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+		r.NoError(err)
+		r.NoError(out.Connect(ctx))
+		t.Cleanup(func() {
+			r.NoError(out.Close(ctx))
+		})
+		return out
+	}
+	batch := service.MessageBatch{service.NewMessage([]byte(`{}`))}
+
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	r.ErrorAs(newOutput("error").WriteBatch(ctx, batch), &alreadyStarted)
+	r.NoError(newOutput("ack").WriteBatch(ctx, batch))
+
+	time.AfterFunc(500*time.Millisecond, func() {
+		_ = c.SignalWorkflow(ctx, run.GetID(), run.GetRunID(), "done", "yes")
+	})
+	r.NoError(newOutput("attach_and_wait").WriteBatch(ctx, batch))
+	var done string
+	r.NoError(run.Get(ctx, &done))
+	r.Equal("yes", done)
+}
+
+// testFallbackOutput records the messages routed to it by a fallback output.
+type testFallbackOutput struct {
+	msgs chan *service.Message
+}
+
+func (o *testFallbackOutput) Connect(context.Context) error {
+	return nil
+}
+
+func (o *testFallbackOutput) Write(ctx context.Context, msg *service.Message) error {
+	o.msgs <- msg
+	return nil
+}
+
+func (o *testFallbackOutput) Close(context.Context) error {
+	return nil
+}
+
+func TestConnectWorkflowOutput_OnWorkflowFailure(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) error {
+		return temporal.NewNonRetryableApplicationError("invalid input", "BadInput", nil)
+	}, workflow.RegisterOptions{Name: "failing"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	// run writes batch to a temporal_workflow output wrapped in a fallback
+	// output, returning the messages routed to the fallback
+	run := func(onWorkflowFailure string, batch service.MessageBatch) []*service.Message {
+		fallback := &testFallbackOutput{msgs: make(chan *service.Message, len(batch))}
+		env := service.NewEnvironment()
+		r.NoError(env.RegisterOutput("test_fallback", service.NewConfigSpec(), func(*service.ParsedConfig, *service.Resources) (service.Output, int, error) {
+			return fallback, 1, nil
+		}))
+		builder := env.NewStreamBuilder()
+		builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+		producer, err := builder.AddBatchProducerFunc()
+		r.NoError(err)
+		r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+fallback:
+  - temporal_workflow:
+      address: %s
+      task_queue: test
+      workflow_id: failing/${! this.id }
+      workflow_type: failing
+      on_workflow_failure: %s
+  - test_fallback: {}
+`, srv.FrontendHostPort(), onWorkflowFailure)))
+		stream, err := builder.Build()
+		r.NoError(err)
+
+		var g sync.WaitGroup
+		g.Add(1)
+		go func() {
+			defer g.Done()
+			r.NoError(stream.Run(ctx))
+		}()
+		r.NoError(producer(ctx, batch))
+		r.NoError(stream.Stop(ctx))
+		g.Wait()
+
+		close(fallback.msgs)
+		var msgs []*service.Message
+		for msg := range fallback.msgs {
+			msgs = append(msgs, msg)
+		}
+		return msgs
+	}
+
+	r.Empty(run("ack", service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"ack"}`)),
+	}))
+
+	// nack is an alias of error
+	msgs := append(run("error", service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b"}`)),
+	}), run("nack", service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"c"}`)),
+	})...)
+	r.Len(msgs, 3)
+	for _, msg := range msgs {
+		fallbackErr, ok := msg.MetaGet("fallback_error")
+		r.True(ok)
+		var failure map[string]any
+		r.NoError(json.Unmarshal([]byte(fallbackErr), &failure), fallbackErr)
+		structured, err := msg.AsStructured()
+		r.NoError(err)
+		id := structured.(map[string]any)["id"]
+		r.Equal(fmt.Sprintf("failing/%s", id), failure["workflow_id"])
+		r.NotEmpty(failure["run_id"])
+		r.Equal("invalid input", failure["workflow_failure_message"])
+		r.Equal(true, failure["workflow_failure_non_retryable"])
+		r.Equal("BadInput", failure["workflow_failure_type"])
+	}
+}
+
+func TestConnectWorkflowProcessor_OnWorkflowFailure(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return nil, temporal.NewApplicationError("processing failed", "Failed", nil)
+	}, workflow.RegisterOptions{Name: "failing_processor"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	for _, mode := range []string{"ack", "error", "nack"} {
+		t.Run(mode, func(t *testing.T) {
+			r := require.New(t)
+			spec := plugin.NewWorkflowProcessorConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+			parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: failing_processor/%s
+workflow_type: failing_processor
+on_workflow_failure: %s
+`, srv.FrontendHostPort(), mode, mode), nil)
+			r.NoError(err)
+			proc, err := plugin.NewWorkflowProcessor(parsed, service.MockResources(), connect.MessageBatch)
+			r.NoError(err)
+			t.Cleanup(func() {
+				r.NoError(proc.Close(ctx))
+			})
+
+			batch, err := proc.Process(ctx, service.NewMessage([]byte(`{"foo":"bar"}`)))
+			if mode == "nack" {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Len(batch, 1)
+			b, err := batch[0].AsBytes()
+			r.NoError(err)
+			r.JSONEq(`{"foo":"bar"}`, string(b))
+			typ, _ := batch[0].MetaGet("workflow_failure_type")
+			r.Equal("Failed", typ)
+			if mode == "error" {
+				r.Error(batch[0].GetError())
+			} else {
+				r.NoError(batch[0].GetError())
+			}
+		})
+	}
+}
+
+func TestConnectWorkflowOutput_SDKMetrics(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: sdk_metrics
+workflow_type: sdk_metrics
+detach: "true"
+`, srv.FrontendHostPort()), nil)
+	r.NoError(err)
+	mgr := newTestResources()
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, mgr, connect.BatchError)
+	r.NoError(err)
+	r.NoError(out.Connect(ctx))
+	t.Cleanup(func() {
+		r.NoError(out.Close(ctx))
+	})
+
+	r.NoError(out.WriteBatch(ctx, service.MessageBatch{service.NewMessage([]byte(`{}`))}))
+	r.NoError(c.TerminateWorkflow(ctx, "sdk_metrics", "", "test complete"))
+
+	mgr.metrics.mu.Lock()
+	defer mgr.metrics.mu.Unlock()
+	r.Equal("StartWorkflowExecution", mgr.metrics.counters["temporal_request"]["operation"])
+	r.Equal("StartWorkflowExecution", mgr.metrics.timers["temporal_request_latency"]["operation"])
+}
+
+func TestConnectWorkflowOutput_Metrics(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) error {
+		if input["fail"] == true {
+			return temporal.NewNonRetryableApplicationError("failed", "Failed", nil)
+		}
+		return nil
+	}, workflow.RegisterOptions{Name: "metrics"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: metrics/${! this.id.not_null() }
+workflow_type: metrics
+on_workflow_failure: ack
+`, srv.FrontendHostPort()), nil)
+	r.NoError(err)
+	mgr := newTestResources()
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, mgr, connect.BatchError)
+	r.NoError(err)
+	r.NoError(out.Connect(ctx))
+	t.Cleanup(func() {
+		r.NoError(out.Close(ctx))
+	})
+
+	r.Error(out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"ok"}`)),
+		service.NewMessage([]byte(`{"id":"fail","fail":true}`)),
+		service.NewMessage([]byte(`{}`)),
+	}))
+
+	mgr.metrics.mu.Lock()
+	defer mgr.metrics.mu.Unlock()
+	labels := map[string]string{"workflow_type": "metrics", "task_queue": "test"}
+	for name, count := range map[string]int64{
+		"temporal_workflow_started":            2,
+		"temporal_workflow_start_error":        1,
+		"temporal_workflow_failed":             1,
+		"temporal_workflow_start_latency":      2,
+		"temporal_workflow_completion_latency": 2,
+	} {
+		r.Equal(count, mgr.metrics.counts[name], name)
+	}
+	r.Equal(labels, mgr.metrics.counters["temporal_workflow_started"])
+	r.Equal(labels, mgr.metrics.timers["temporal_workflow_completion_latency"])
+	r.Zero(mgr.metrics.counts["temporal_workflow_already_started"])
+}
+
+func TestConnectWorkflowOutput_Tracing(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracing, err := opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{
+		Tracer:            tp.Tracer("test"),
+		TextMapPropagator: propagation.TraceContext{},
+	})
+	r.NoError(err)
+
+	w := worker.New(c, "tracing", worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{tracing},
+	})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) error {
+		return nil
+	}, workflow.RegisterOptions{Name: "tracing"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: tracing
+workflow_id: tracing
+workflow_type: tracing
+tracing: true
+`, srv.FrontendHostPort()), nil)
+	r.NoError(err)
+	mgr := newTestResources()
+	mgr.tracer = tp
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, mgr, connect.BatchError)
+	r.NoError(err)
+	r.NoError(out.Connect(ctx))
+	t.Cleanup(func() {
+		r.NoError(out.Close(ctx))
+	})
+
+	msg := service.NewMessage([]byte(`{}`))
+	msg.MetaSetMut("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.NoError(out.WriteBatch(ctx, service.MessageBatch{msg}))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	for _, name := range []string{"StartWorkflow:tracing", "RunWorkflow:tracing"} {
+		span, ok := spans[name]
+		r.True(ok, name)
+		r.Equal("4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), name)
+	}
+	r.Equal("00f067aa0ba902b7", spans["StartWorkflow:tracing"].Parent().SpanID().String())
+}
+
+func TestConnectWorkflowOutput_PropagateMetadata(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c, err := client.Dial(client.Options{
+		HostPort:           srv.FrontendHostPort(),
+		ContextPropagators: []workflow.ContextPropagator{plugin.NewMetadataPropagator("tenant_id", "tenant_secret", "request_id", "other")},
+	})
+	r.NoError(err)
+	t.Cleanup(c.Close)
+
+	w := worker.New(c, "propagate_metadata", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) (map[string]string, error) {
+		return plugin.MetadataFromWorkflowContext(ctx), nil
+	}, workflow.RegisterOptions{Name: "propagate_metadata"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	// propagate executes a workflow using the propagate_metadata field conf,
+	// returning the metadata received by the workflow
+	propagate := func(conf string) map[string]string {
+		id := "propagate_metadata/" + uuid.NewString()
+		spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+		parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: propagate_metadata
+workflow_id: %s
+workflow_type: propagate_metadata
+args: root = []
+propagate_metadata: %s
+`, srv.FrontendHostPort(), id, conf), nil)
+		r.NoError(err)
+		out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+		r.NoError(err)
+		r.NoError(out.Connect(ctx))
+		t.Cleanup(func() {
+			r.NoError(out.Close(ctx))
+		})
+
+		msg := service.NewMessage([]byte(`{}`))
+		msg.MetaSetMut("tenant_id", "acme")
+		msg.MetaSetMut("tenant_secret", "hunter2")
+		msg.MetaSetMut("request_id", "abc123")
+		msg.MetaSetMut("other", "ignored")
+		r.NoError(out.WriteBatch(ctx, service.MessageBatch{msg}))
+
+		var md map[string]string
+		r.NoError(c.GetWorkflow(ctx, id, "").Get(ctx, &md))
+		return md
+	}
+
+	r.Equal(map[string]string{"tenant_id": "acme", "request_id": "abc123"}, propagate(`
+  include_prefixes: [tenant_]
+  include_patterns: ["^request_id$"]
+  exclude_patterns: ["secret"]`))
+	r.Equal(map[string]string{"tenant_id": "acme", "request_id": "abc123", "other": "ignored"}, propagate(`
+  exclude_patterns: ["secret"]`))
+	// an empty object cannot be distinguished from an omitted one
+	r.Empty(propagate(`{}`))
+}
+
+// testResources provides component resources that record the labels of the
+// most recent observation of each metric, and the total of each counter.
+type testResources struct {
+	metrics *testMetrics
+	tracer  trace.TracerProvider
+}
+
+func TestConnectClientResource(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c := srv.Client()
+
+	w := worker.New(c, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return map[string]any{"greeting": fmt.Sprintf("hello %s", input["name"])}, nil
+	}, workflow.RegisterOptions{Name: "greet"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	r.NoError(builder.AddCacheYAML(fmt.Sprintf(`
+label: shared
+temporal_client:
+  address: %s
+`, srv.FrontendHostPort())))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddProcessorYAML(`
+temporal_workflow:
+  client_resource: shared
+  task_queue: test
+  workflow_id: greet/${! this.name }
+  workflow_type: greet
+`))
+	r.NoError(builder.AddOutputYAML(`
+temporal_workflow:
+  client_resource: shared
+  mapping: 'root.name = this.greeting'
+  task_queue: test
+  workflow_id: greet/${! this.greeting }
+  workflow_type: greet
+`))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"name":"world"}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	var result map[string]any
+	r.NoError(c.GetWorkflow(ctx, "greet/hello world", "").Get(ctx, &result))
+	r.Equal("hello hello world", result["greeting"])
+}
+
+func TestConnectClientResource_NotFound(t *testing.T) {
+	r, ctx := require.New(t), context.Background()
+
+	spec := plugin.NewSignalOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(`
+client_resource: missing
+signal_name: test
+workflow_id: test
+`, nil)
+	r.NoError(err)
+	out, _, err := plugin.NewSignalOutput(parsed, service.MockResources())
+	r.NoError(err)
+	r.ErrorContains(out.Connect(ctx), "error accessing client_resource missing")
+}
+
+func TestConnectClientResource_ConnectionFields(t *testing.T) {
+	for name, conf := range map[string]string{
+		"codec auth": `codec_auth: Bearer secret`,
+		"namespace":  `namespace: tenant`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			spec := plugin.NewSignalOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+			parsed, err := spec.ParseYAML(`
+client_resource: shared
+signal_name: test
+workflow_id: test
+`+conf, nil)
+			require.NoError(t, err)
+			_, _, err = plugin.NewSignalOutput(parsed, service.MockResources())
+			require.ErrorContains(t, err, "cannot specify address, auth, codec_auth, codec_endpoint, codecs, encryption, env_config, namespace or tls with client_resource")
+		})
+	}
+}
+
+func TestConnectWorkflowOutput_NamespaceRouting(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	tenant, err := client.Dial(client.Options{
+		HostPort:  srv.FrontendHostPort(),
+		Namespace: "tenant",
+	})
+	r.NoError(err)
+	t.Cleanup(tenant.Close)
+
+	for _, c := range []client.Client{srv.Client(), tenant} {
+		w := worker.New(c, "test", worker.Options{})
+		w.RegisterWorkflowWithOptions(func(ctx workflow.Context) (string, error) {
+			return workflow.GetInfo(ctx).Namespace, nil
+		}, workflow.RegisterOptions{Name: "routing"})
+		r.NoError(w.Start())
+		t.Cleanup(w.Stop)
+	}
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  namespace: ${! meta("namespace") }
+  client_pool:
+    max_clients: 1
+  mapping: 'root = deleted()'
+  task_queue: test
+  workflow_id: routing/${! meta("namespace") }
+  workflow_type: routing
+`, srv.FrontendHostPort())))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	for _, namespace := range []string{"default", "tenant", "default"} {
+		msg := service.NewMessage(nil)
+		msg.MetaSetMut("namespace", namespace)
+		r.NoError(producer(ctx, msg))
+	}
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	for namespace, c := range map[string]client.Client{"default": srv.Client(), "tenant": tenant} {
+		var result string
+		r.NoError(c.GetWorkflow(ctx, "routing/"+namespace, "").Get(ctx, &result))
+		r.Equal(namespace, result)
+	}
+}
+
+func TestConnectWorkflowOutput_ClientPoolClosed(t *testing.T) {
+	r, ctx := require.New(t), context.Background()
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(`
+address: localhost:7233
+namespace: ${! meta("namespace") }
+task_queue: test
+workflow_id: test
+workflow_type: test
+`, nil)
+	r.NoError(err)
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+	r.NoError(err)
+	r.NoError(out.Connect(ctx))
+	r.NoError(out.Close(ctx))
+
+	msg := service.NewMessage([]byte(`{}`))
+	msg.MetaSetMut("namespace", "tenant")
+	r.ErrorContains(out.WriteBatch(ctx, service.MessageBatch{msg}), "client pool is closed")
+}
+
+func TestConnectWorkflowProcessor_Encryption(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	keys := map[string][]byte{
+		"key-1": []byte("test-key-test-key-test-key-test1"),
+		"key-2": []byte("test-key-test-key-test-key-test2"),
+	}
+	keyFile := filepath.Join(t.TempDir(), "key-2")
+	r.NoError(os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(keys["key-2"])+"\n"), 0o600))
+
+	// the worker encrypts results with the previous key, as if it had not yet
+	// been rotated
+	c, err := client.NewClientFromExisting(srv.Client(), client.Options{
+		DataConverter: converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), &testEncryptionCodec{keyID: "key-1", keys: keys}),
+	})
+	r.NoError(err)
+	t.Cleanup(c.Close)
+
+	w := worker.New(c, "encrypted", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return map[string]any{"greeting": fmt.Sprintf("hello %s", input["name"])}, nil
+	}, workflow.RegisterOptions{Name: "greet"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddProcessorYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  encryption:
+    key_id: key-2
+    key_files:
+      key-2: %s
+    keys:
+      key-1: %s
+  task_queue: encrypted
+  workflow_id: encrypted/${! this.name }
+  workflow_type: greet
+`, srv.FrontendHostPort(), keyFile, hex.EncodeToString(keys["key-1"]))))
+
+	var mu sync.Mutex
+	var results []*service.Message
+	r.NoError(builder.AddConsumerFunc(func(ctx context.Context, msg *service.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, msg)
+		return nil
+	}))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage([]byte(`{"name":"world"}`))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	r.Len(results, 1)
+	r.NoError(results[0].GetError())
+	b, err := results[0].AsBytes()
+	r.NoError(err)
+	r.JSONEq(`{"greeting":"hello world"}`, string(b))
+
+	iter := srv.Client().GetWorkflowHistory(ctx, "encrypted/world", "", false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	r.True(iter.HasNext())
+	event, err := iter.Next()
+	r.NoError(err)
+	input := event.GetWorkflowExecutionStartedEventAttributes().GetInput().GetPayloads()
+	r.Len(input, 1)
+	r.Equal("binary/encrypted", string(input[0].GetMetadata()["encoding"]))
+	r.Equal("key-2", string(input[0].GetMetadata()["encryption-key-id"]))
+}
+
+func TestConnectWorkflowProcessor_Codecs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	c, err := client.NewClientFromExisting(srv.Client(), client.Options{
+		DataConverter: converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), converter.NewZlibCodec(converter.ZlibCodecOptions{})),
+	})
+	r.NoError(err)
+	t.Cleanup(c.Close)
+
+	w := worker.New(c, "compressed", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+		return input, nil
+	}, workflow.RegisterOptions{Name: "echo"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddProcessorYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  codecs: [zlib]
+  task_queue: compressed
+  workflow_id: compressed/${! this.id }
+  workflow_type: echo
+`, srv.FrontendHostPort())))
+
+	var mu sync.Mutex
+	var results []*service.Message
+	r.NoError(builder.AddConsumerFunc(func(ctx context.Context, msg *service.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		results = append(results, msg)
+		return nil
+	}))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	body := fmt.Sprintf(`{"id":"1","data":%q}`, strings.Repeat("temporal", 1000))
+	r.NoError(producer(ctx, service.NewMessage([]byte(body))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	r.Len(results, 1)
+	r.NoError(results[0].GetError())
+	b, err := results[0].AsBytes()
+	r.NoError(err)
+	r.JSONEq(body, string(b))
+
+	iter := srv.Client().GetWorkflowHistory(ctx, "compressed/1", "", false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	r.True(iter.HasNext())
+	event, err := iter.Next()
+	r.NoError(err)
+	input := event.GetWorkflowExecutionStartedEventAttributes().GetInput().GetPayloads()
+	r.Len(input, 1)
+	r.Equal("binary/zlib", string(input[0].GetMetadata()["encoding"]))
+	r.Less(len(input[0].GetData()), len(body))
+}
+
+func TestConnectWorkflowOutput_CodecOrder(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	key := []byte("test-key-test-key-test-key-test1")
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  address: %s
+  codecs: [snappy, encryption]
+  encryption:
+    key_id: key-1
+    keys:
+      key-1: %s
+  detach: "true"
+  task_queue: codec-order
+  workflow_id: codec-order
+  workflow_type: echo
+`, srv.FrontendHostPort(), hex.EncodeToString(key))))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	body := fmt.Sprintf(`{"data":%q}`, strings.Repeat("temporal", 1000))
+	r.NoError(producer(ctx, service.NewMessage([]byte(body))))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	iter := srv.Client().GetWorkflowHistory(ctx, "codec-order", "", false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	r.True(iter.HasNext())
+	event, err := iter.Next()
+	r.NoError(err)
+	input := event.GetWorkflowExecutionStartedEventAttributes().GetInput().GetPayloads()
+	r.Len(input, 1)
+	r.Equal("binary/encrypted", string(input[0].GetMetadata()["encoding"]))
+
+	// payloads are compressed before they are encrypted
+	decrypted, err := (&testEncryptionCodec{keys: map[string][]byte{"key-1": key}}).Decode(input)
+	r.NoError(err)
+	r.Equal("binary/snappy", string(decrypted[0].GetMetadata()["encoding"]))
+	r.Less(len(decrypted[0].GetData()), len(body))
+	r.NoError(srv.Client().TerminateWorkflow(ctx, "codec-order", "", "test complete"))
+}
+
+// testEncryptionCodec implements the Temporal samples' encryption codec.
+type testEncryptionCodec struct {
+	keyID string
+	keys  map[string][]byte
+}
+
+func (c *testEncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(c.keys[c.keyID])
+		if err != nil {
+			return nil, err
+		}
+		aesgcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aesgcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte("binary/encrypted"),
+				"encryption-key-id":        []byte(c.keyID),
+			},
+			Data: aesgcm.Seal(nonce, nonce, b, nil),
+		}
+	}
+	return result, nil
+}
+
+func (c *testEncryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.Metadata[converter.MetadataEncoding]) != "binary/encrypted" {
+			result[i] = p
+			continue
+		}
+		block, err := aes.NewCipher(c.keys[string(p.Metadata["encryption-key-id"])])
+		if err != nil {
+			return nil, err
+		}
+		aesgcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce, ciphertext := p.Data[:aesgcm.NonceSize()], p.Data[aesgcm.NonceSize():]
+		b, err := aesgcm.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func TestConnectWorkflowOutput_EnvConfig(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	r, ctx := require.New(t), context.Background()
+
+	tenant, err := client.Dial(client.Options{
+		HostPort:  srv.FrontendHostPort(),
+		Namespace: "tenant",
+	})
+	r.NoError(err)
+	t.Cleanup(tenant.Close)
+
+	w := worker.New(tenant, "test", worker.Options{})
+	w.RegisterWorkflowWithOptions(func(ctx workflow.Context) (string, error) {
+		return workflow.GetInfo(ctx).Namespace, nil
+	}, workflow.RegisterOptions{Name: "env-config"})
+	r.NoError(w.Start())
+	t.Cleanup(w.Stop)
+
+	configFile := filepath.Join(t.TempDir(), "temporal.toml")
+	r.NoError(os.WriteFile(configFile, []byte(fmt.Sprintf(`
+[profile.dev]
+address = %q
+namespace = "tenant"
+`, srv.FrontendHostPort())), 0o600))
+
+	builder := service.NewStreamBuilder()
+	builder.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	producer, err := builder.AddProducerFunc()
+	r.NoError(err)
+	r.NoError(builder.AddOutputYAML(fmt.Sprintf(`
+temporal_workflow:
+  env_config:
+    config_file: %s
+    profile: dev
+  mapping: 'root = deleted()'
+  task_queue: test
+  workflow_id: env-config
+  workflow_type: env-config
+`, configFile)))
+	stream, err := builder.Build()
+	r.NoError(err)
+
+	var g sync.WaitGroup
+	g.Add(1)
+	go func() {
+		defer g.Done()
+		r.NoError(stream.Run(ctx))
+	}()
+
+	r.NoError(producer(ctx, service.NewMessage(nil)))
+	r.NoError(stream.Stop(ctx))
+	g.Wait()
+
+	var result string
+	r.NoError(tenant.GetWorkflow(ctx, "env-config", "").Get(ctx, &result))
+	r.Equal("tenant", result)
+}
+
+func TestConnectWorkflowOutput_EnvConfigTLS(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "server", ca, caKey, "temporal.example.com")
+	clientCert, clientKey := newTestCertificate(t, "client", ca, caKey)
+	writeTestCertificate(t, filepath.Join(dir, "client.pem"), clientCert, clientKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	clients := make(chan string, 1)
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		})),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			p, _ := peer.FromContext(stream.Context())
+			select {
+			case clients <- p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Subject.CommonName:
+			default:
+			}
+			return status.Error(codes.Unimplemented, "not implemented")
+		}),
+	)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	// the profile's CA and client certificate are kept when only the server
+	// name is overridden
+	configFile := filepath.Join(dir, "temporal.toml")
+	r.NoError(os.WriteFile(configFile, []byte(fmt.Sprintf(`
+[profile.default]
+address = %q
+
+[profile.default.tls]
+client_cert_path = %q
+client_key_path = %q
+server_ca_cert_data = %q
+`, lis.Addr(), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}))), 0o600))
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+env_config:
+  config_file: %s
+task_queue: test
+workflow_id: test
+workflow_type: test
+tls:
+  server_name: temporal.example.com
+`, configFile), nil)
+	r.NoError(err)
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+	r.NoError(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r.NoError(out.Connect(ctx))
+	r.NoError(out.Close(ctx))
+	select {
+	case cn := <-clients:
+		r.Equal("client", cn)
+	case <-ctx.Done():
+		r.FailNow("timed out waiting for request")
+	}
+}
+
+func TestConnectWorkflowOutput_Auth(t *testing.T) {
+	r := require.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	requests := make(chan metadata.MD, 1)
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		select {
+		case requests <- md:
+		default:
+		}
+		return status.Error(codes.Unimplemented, "not implemented")
+	}))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	apiKeyFile := filepath.Join(t.TempDir(), "api_key")
+	r.NoError(os.WriteFile(apiKeyFile, []byte("secret\n"), 0o600))
+	configFile := filepath.Join(t.TempDir(), "temporal.toml")
+	r.NoError(os.WriteFile(configFile, []byte(`
+[profile.cloud]
+address = "127.0.0.1:1"
+api_key = "profile-secret"
+
+[profile.cloud.grpc_meta]
+x-region = "us"
+x-tenant = "profile"
+
+[profile.cloud.tls]
+disabled = true
+`), 0o600))
+
+	for name, c := range map[string]struct {
+		conf    string
+		err     string
+		headers map[string]string
+	}{
+		"api key file and headers": {
+			conf: fmt.Sprintf(`
+auth:
+  api_key_file: %s
+  headers:
+    x-tenant: acme
+tls:
+  enabled: false`, apiKeyFile),
+			headers: map[string]string{"authorization": "Bearer secret", "x-tenant": "acme"},
+		},
+		"env config": {
+			conf: fmt.Sprintf(`
+env_config:
+  config_file: %s
+  profile: cloud`, configFile),
+			headers: map[string]string{"authorization": "Bearer profile-secret", "x-region": "us", "x-tenant": "profile"},
+		},
+		"env config overridden": {
+			conf: fmt.Sprintf(`
+auth:
+  api_key: secret
+  headers:
+    x-tenant: acme
+env_config:
+  config_file: %s
+  profile: cloud`, configFile),
+			headers: map[string]string{"authorization": "Bearer secret", "x-region": "us", "x-tenant": "acme"},
+		},
+		"api key enables tls": {
+			conf: `
+auth:
+  api_key: secret`,
+			err: "tls",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+			parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: test
+workflow_type: test
+`, lis.Addr())+c.conf, nil)
+			r.NoError(err)
+			out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+			r.NoError(err)
+			err = out.Connect(ctx)
+			t.Cleanup(func() { out.Close(context.Background()) })
+			if c.err != "" {
+				r.ErrorContains(err, c.err)
+				r.Empty(requests)
+				return
+			}
+			select {
+			case md := <-requests:
+				for k, v := range c.headers {
+					r.Equal([]string{v}, md.Get(k))
+				}
+			case <-ctx.Done():
+				r.FailNow("timed out waiting for request")
+			}
+		})
+	}
+}
+
+func TestConnectWorkflowOutput_TLSReload(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "server", ca, caKey)
+	writeTestCertificate(t, filepath.Join(dir, "ca.pem"), ca, nil)
+	clientCert, clientKey := newTestCertificate(t, "client-a", ca, caKey)
+	writeTestCertificate(t, filepath.Join(dir, "client.pem"), clientCert, clientKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	clients := make(chan string, 1)
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+		})),
+		grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+			p, _ := peer.FromContext(stream.Context())
+			select {
+			case clients <- p.AuthInfo.(credentials.TLSInfo).State.PeerCertificates[0].Subject.CommonName:
+			default:
+			}
+			return status.Error(codes.Unimplemented, "not implemented")
+		}),
+	)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+	parsed, err := spec.ParseYAML(fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: test
+workflow_type: test
+tls:
+  ca_file: %s
+  cert_file: %s
+  key_file: %s
+`, lis.Addr(), filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.pem")), nil)
+	r.NoError(err)
+	mgr := newTestResources()
+	out, _, _, err := plugin.NewWorkflowOutput(parsed, mgr, connect.BatchError)
+	r.NoError(err)
+
+	connectAs := func(expected string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = out.Connect(ctx)
+		defer out.Close(ctx)
+		select {
+		case cn := <-clients:
+			r.Equal(expected, cn)
+		case <-ctx.Done():
+			r.FailNow("timed out waiting for request")
+		}
+	}
+	// rotate replaces the client certificate, advancing its modification time
+	// since writes may occur within the file system's timestamp resolution
+	rotate := func(write func(path string), offset time.Duration) {
+		path := filepath.Join(dir, "client.pem")
+		write(path)
+		at := time.Now().Add(offset)
+		r.NoError(os.Chtimes(path, at, at))
+	}
+
+	connectAs("client-a")
+
+	rotate(func(path string) {
+		clientCert, clientKey := newTestCertificate(t, "client-b", ca, caKey)
+		writeTestCertificate(t, path, clientCert, clientKey)
+	}, time.Minute)
+	connectAs("client-b")
+	r.Zero(mgr.metrics.counts["temporal_tls_reload_error"])
+
+	rotate(func(path string) {
+		r.NoError(os.WriteFile(path, []byte("invalid"), 0o600))
+	}, 2*time.Minute)
+	connectAs("client-b")
+	r.NotZero(mgr.metrics.counts["temporal_tls_reload_error"])
+}
+
+func TestConnectWorkflowOutput_TLSReloadServerName(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newTestCertificate(t, "ca", nil, nil)
+	serverCert, serverKey := newTestCertificate(t, "server", ca, caKey, "temporal.example.com")
+	writeTestCertificate(t, filepath.Join(dir, "ca.pem"), ca, nil)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(&tls.Config{
+			Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		})),
+		grpc.UnknownServiceHandler(func(any, grpc.ServerStream) error {
+			return status.Error(codes.Unimplemented, "not implemented")
+		}),
+	)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	for name, c := range map[string]struct {
+		serverName string
+		err        string
+	}{
+		"dialed host": {
+			err: "x509: cannot validate certificate for 127.0.0.1",
+		},
+		"server name": {
+			serverName: "temporal.example.com",
+		},
+		"invalid server name": {
+			serverName: "other.example.com",
+			err:        "x509: certificate is valid for temporal.example.com, not other.example.com",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conf := fmt.Sprintf(`
+address: %s
+task_queue: test
+workflow_id: test
+workflow_type: test
+tls:
+  ca_file: %s
+`, lis.Addr(), filepath.Join(dir, "ca.pem"))
+			if c.serverName != "" {
+				conf += fmt.Sprintf("  server_name: %s\n", c.serverName)
+			}
+			spec := plugin.NewWorkflowOutputConfig(service.NewConfigSpec(), connect.DefaultFieldProvider)
+			parsed, err := spec.ParseYAML(conf, nil)
+			require.NoError(t, err)
+			out, _, _, err := plugin.NewWorkflowOutput(parsed, service.MockResources(), connect.BatchError)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := out.Connect(ctx); c.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, c.err)
+			}
+			require.NoError(t, out.Close(ctx))
+		})
+	}
+}
+
+func newTestResources() *testResources {
+	return &testResources{
+		metrics: &testMetrics{
+			counters: map[string]map[string]string{},
+			counts:   map[string]int64{},
+			timers:   map[string]map[string]string{},
+		},
+		tracer: noop.NewTracerProvider(),
+	}
+}
+
+func (r *testResources) AccessCache(ctx context.Context, name string, fn func(service.Cache)) error {
+	return fmt.Errorf("cache resource %s not found", name)
+}
+
+func (r *testResources) Logger() *service.Logger {
+	return nil
+}
+
+func (r *testResources) Metrics() *testMetrics {
+	return r.metrics
+}
+
+func (r *testResources) OtelTracer() trace.TracerProvider {
+	return r.tracer
+}
+
+type testMetrics struct {
+	mu       sync.Mutex
+	counters map[string]map[string]string
+	counts   map[string]int64
+	timers   map[string]map[string]string
+}
+
+func (m *testMetrics) NewCounter(name string, keys ...string) *testMetric {
+	return &testMetric{m: m, name: name, keys: keys, observed: m.counters}
+}
+
+func (m *testMetrics) NewGauge(name string, keys ...string) *testMetric {
+	return &testMetric{m: m, name: name, keys: keys, observed: map[string]map[string]string{}}
+}
+
+func (m *testMetrics) NewTimer(name string, keys ...string) *testMetric {
+	return &testMetric{m: m, name: name, keys: keys, observed: m.timers}
+}
+
+type testMetric struct {
+	m        *testMetrics
+	name     string
+	keys     []string
+	observed map[string]map[string]string
+}
+
+func (m *testMetric) observe(count int64, values []string) {
+	m.m.mu.Lock()
+	defer m.m.mu.Unlock()
+	m.m.counts[m.name] += count
+	labels := make(map[string]string, len(m.keys))
+	for i, k := range m.keys {
+		labels[k] = values[i]
+	}
+	m.observed[m.name] = labels
+}
+
+func (m *testMetric) Incr(count int64, values ...string) {
+	m.observe(count, values)
+}
+
+func (m *testMetric) Set(_ int64, values ...string) {
+	m.observe(0, values)
+}
+
+func (m *testMetric) Timing(_ int64, values ...string) {
+	m.observe(1, values)
+}
+
+// newTestCertificate returns a certificate for the given DNS names, or
+// 127.0.0.1 if none are specified, with the given common name, which is
+// self-signed if parent is nil.
+func newTestCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, dnsNames ...string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotAfter:              time.Now().Add(time.Hour),
+		NotBefore:             time.Now().Add(-time.Hour),
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+	}
+	if len(dnsNames) > 0 {
+		template.DNSNames, template.IPAddresses = dnsNames, nil
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+// writeTestCertificate writes a PEM-encoded certificate to path, followed by
+// the private key if specified.
+func writeTestCertificate(t *testing.T, path string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	b := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if key != nil {
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		b = append(b, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})...)
+	}
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/cludden/protoc-gen-go-temporal/pkg/scheme"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// newArgs returns the structured contents of msg as Temporal payload
+// arguments, or no arguments if a mapping deleted the message.
+func newArgs[
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+](msg Message) ([]any, error) {
+	var empty Message
+	if reflect.DeepEqual(msg, empty) {
+		return nil, nil
+	}
+	arg, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating message as structured: %w", err)
+	}
+	return []any{arg}, nil
+}
+
+// newPositionalArgs spreads the array contents of msg into positional Temporal
+// payload arguments. An empty array, or a mapping that deleted the message,
+// results in no arguments. When a scheme is provided, each argument with a
+// corresponding non-empty entry in messageTypes is converted to that proto
+// message.
+func newPositionalArgs[
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+](msg Message, s *scheme.Scheme, messageTypes []string) ([]any, error) {
+	var empty Message
+	if reflect.DeepEqual(msg, empty) {
+		return nil, nil
+	}
+	v, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating args as structured: %w", err)
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected args to return an array, got: %T", v)
+	}
+	if len(messageTypes) > len(items) {
+		return nil, fmt.Errorf("expected at least %d args, got: %d", len(messageTypes), len(items))
+	}
+	args := make([]any, len(items))
+	for i, item := range items {
+		if s == nil || i >= len(messageTypes) || messageTypes[i] == "" {
+			args[i] = item
+			continue
+		}
+		pb, err := s.New(messageTypes[i])
+		if err != nil {
+			return nil, fmt.Errorf("error initializing new %s value for arg %d: %w", messageTypes[i], i, err)
+		}
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("error serializing arg %d: %w", i, err)
+		}
+		if err := protojson.Unmarshal(b, pb); err != nil {
+			return nil, fmt.Errorf("error unmarshalling arg %d as %s: %w", i, messageTypes[i], err)
+		}
+		args[i] = pb
+	}
+	return args, nil
+}
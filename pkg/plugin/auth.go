@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.temporal.io/sdk/client"
+)
+
+// headersProvider sets static gRPC metadata on every request.
+type headersProvider map[string]string
+
+func (h headersProvider) GetHeaders(context.Context) (map[string]string, error) {
+	return h, nil
+}
+
+// newAuthConfigField returns the auth field used to authenticate with a
+// Temporal cluster using an API key or custom gRPC metadata.
+func newAuthConfigField[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewObjectField(string, ...Field) Field
+		NewStringField(string) Field
+		NewStringMapField(string) Field
+	},
+](fields FieldProvider) Field {
+	return fields.NewObjectField("auth",
+		fields.NewStringField("api_key").
+			Description("API key used to authenticate with Temporal Cloud, which enables TLS unless tls.enabled is false").
+			Optional(),
+		fields.NewStringField("api_key_file").
+			Description("Path to a file containing the API key, which is read on each request so that rotated keys are used without a restart").
+			Optional(),
+		fields.NewStringMapField("headers").
+			Description("gRPC metadata sent with every request").
+			Optional(),
+	).
+		Description("Optional authentication configuration").
+		Optional()
+}
+
+// parseAuth parses the auth field into the credentials and headers provider
+// of opts.
+func parseAuth(conf interface {
+	Contains(...string) bool
+	FieldString(...string) (string, error)
+	FieldStringMap(...string) (map[string]string, error)
+}, opts *client.Options) error {
+	switch apiKeyExists, apiKeyFileExists := conf.Contains("auth", "api_key"), conf.Contains("auth", "api_key_file"); {
+	case apiKeyExists && apiKeyFileExists:
+		return errors.New("cannot specify both auth.api_key and auth.api_key_file")
+	case apiKeyExists:
+		apiKey, err := conf.FieldString("auth", "api_key")
+		if err != nil {
+			return err
+		}
+		opts.Credentials = client.NewAPIKeyStaticCredentials(apiKey)
+	case apiKeyFileExists:
+		path, err := conf.FieldString("auth", "api_key_file")
+		if err != nil {
+			return err
+		}
+		apiKey := readAPIKey(path)
+		if _, err := apiKey(context.Background()); err != nil {
+			return err
+		}
+		opts.Credentials = client.NewAPIKeyDynamicCredentials(apiKey)
+	}
+	if conf.Contains("auth", "headers") {
+		headers, err := conf.FieldStringMap("auth", "headers")
+		if err != nil {
+			return err
+		}
+		if len(headers) > 0 {
+			opts.HeadersProvider = headersProvider(headers)
+		}
+	}
+	return nil
+}
+
+// readAPIKey returns a callback that reads the API key from path.
+func readAPIKey(path string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading auth.api_key_file: %w", err)
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
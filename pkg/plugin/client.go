@@ -0,0 +1,339 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"maps"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/envconfig"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/log"
+)
+
+// newClientConfigFields returns the client fields shared by all components
+// that communicate with a Temporal cluster, where address and namespace are
+// interpolated if the component supports routing messages to different
+// clients.
+func newClientConfigFields[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](fields FieldProvider, interpolated bool) []Field {
+	return append(newConnectionConfigFields[Field](fields, interpolated),
+		fields.NewStringField("client_resource").
+			Description("Name of a temporal_client resource whose connection is shared, instead of dialing a dedicated connection").
+			Optional(),
+	)
+}
+
+// newConnectionConfigFields returns the fields used to connect to a Temporal
+// cluster, where address and namespace are interpolated if specified.
+func newConnectionConfigFields[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](fields FieldProvider, interpolated bool) []Field {
+	newConnectionField := fields.NewStringField
+	if interpolated {
+		newConnectionField = fields.NewInterpolatedStringField
+	}
+	return []Field{
+		newConnectionField("address").
+			Description("Temporal cluster address, defaults to localhost:7233").
+			Optional(),
+		newAuthConfigField[Field](fields),
+		fields.NewStringField("codec_auth").
+			Description("Authorization header for requests to Codec Server").
+			Optional(),
+		fields.NewStringField("codec_endpoint").
+			Description("Endpoint for remote Codec Server").
+			Optional(),
+		fields.NewStringListField("codecs").
+			Description("Payload codecs applied in order when encoding and in reverse order when decoding, one of encryption, remote, snappy, or zlib, where encryption is configured by the encryption field and remote by codec_endpoint. Defaults to remote then encryption when configured").
+			Optional(),
+		newEncryptionConfigField[Field](fields),
+		newEnvConfigConfigField[Field](fields),
+		newConnectionField("namespace").
+			Description("Temporal namespace name, defaults to default").
+			Optional(),
+		fields.NewObjectField("tls",
+			fields.NewBoolField("enabled").
+				Description("Enables TLS, which defaults to true if any other TLS field or an API key is specified").
+				Optional(),
+			fields.NewStringField("ca_file").
+				Description("Path to ca file").
+				Optional(),
+			fields.NewStringField("ca_data").
+				Description("PEM-encoded ca data").
+				Optional(),
+			fields.NewStringField("cert_file").
+				Description("Path to certificate file").
+				Optional(),
+			fields.NewStringField("cert_data").
+				Description("PEM-encoded certificate data").
+				Optional(),
+			fields.NewBoolField("disable_host_verification").
+				Description("Disable TLS host verification").
+				Optional(),
+			fields.NewStringField("key_file").
+				Description("Path to private key").
+				Optional(),
+			fields.NewStringField("key_data").
+				Description("PEM-encoded private key data").
+				Optional(),
+			fields.NewStringField("server_name").
+				Description("Override target TLS server name").
+				Optional(),
+		).
+			Description("Optional TLS configuration").
+			Optional(),
+	}
+}
+
+// newClientOptions parses the shared connection fields into a copy of opts,
+// which holds the client telemetry, wrapping the provided data converter with
+// any configured codecs. Settings loaded from the env_config profile are
+// overridden by the explicitly configured fields.
+func newClientOptions[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+](conf ParsedConfig, opts client.Options, dc converter.DataConverter) (_ client.Options, err error) {
+	profile, err := loadEnvConfig(conf)
+	if err != nil {
+		return opts, err
+	}
+	var codec envconfig.ClientConfigCodec
+	var headers map[string]string
+	if profile != nil {
+		profileOpts, err := profile.ToClientOptions(envconfig.ToClientOptionsRequest{})
+		if err != nil {
+			return opts, fmt.Errorf("error loading env_config: %w", err)
+		}
+		opts.HostPort, opts.Namespace, opts.Credentials = profileOpts.HostPort, profileOpts.Namespace, profileOpts.Credentials
+		opts.ConnectionOptions.Authority = profileOpts.ConnectionOptions.Authority
+		opts.ConnectionOptions.TLS = profileOpts.ConnectionOptions.TLS
+		opts.ConnectionOptions.TLSDisabled = profileOpts.ConnectionOptions.TLSDisabled
+		if profile.Codec != nil {
+			codec = *profile.Codec
+		}
+		headers = profile.GRPCMeta
+	}
+	if conf.Contains("address") {
+		if opts.HostPort, err = conf.FieldString("address"); err != nil {
+			return opts, err
+		}
+	}
+	if dc == nil {
+		dc = converter.GetDefaultDataConverter()
+	}
+	if conf.Contains("codec_endpoint") {
+		if codec.Endpoint, err = conf.FieldString("codec_endpoint"); err != nil {
+			return opts, err
+		}
+	}
+	if conf.Contains("codec_auth") {
+		if codec.Auth, err = conf.FieldString("codec_auth"); err != nil {
+			return opts, err
+		}
+	}
+	codecs, err := parseCodecs(conf, codec)
+	if err != nil {
+		return opts, err
+	}
+	if len(codecs) > 0 {
+		dc = converter.NewCodecDataConverter(dc, codecs...)
+	}
+	opts.DataConverter = dc
+	if conf.Contains("namespace") {
+		if opts.Namespace, err = conf.FieldString("namespace"); err != nil {
+			return opts, err
+		}
+	}
+	tlsConfig, err := parseTLS[InterpolatedString, Mapping, Message, ParsedConfig](conf, opts.ConnectionOptions.TLS, opts.HostPort, opts.Logger, opts.MetricsHandler)
+	if err != nil {
+		return opts, err
+	}
+	if tlsConfig != nil || conf.Contains("tls", "enabled") {
+		opts.ConnectionOptions.TLS = tlsConfig
+		opts.ConnectionOptions.TLSDisabled = tlsConfig == nil
+	}
+	if err := parseAuth(conf, &opts); err != nil {
+		return opts, err
+	}
+	if len(headers) > 0 {
+		// auth.headers take precedence over the profile's gRPC metadata
+		merged := maps.Clone(headers)
+		if provider, ok := opts.HeadersProvider.(headersProvider); ok {
+			maps.Copy(merged, provider)
+		}
+		opts.HeadersProvider = headersProvider(merged)
+	}
+	if opts.Credentials != nil && opts.ConnectionOptions.TLS == nil && !opts.ConnectionOptions.TLSDisabled {
+		// API keys are only accepted over TLS
+		opts.ConnectionOptions.TLS = &tls.Config{}
+	}
+	return opts, nil
+}
+
+// parseTLS parses the tls field, where certificates and CAs loaded from files
+// are reloaded when the files change, reporting reload failures using logger
+// and metrics. The host of hostPort is used to verify the server certificate
+// when the CA is reloaded and no server name is sent. When base is not nil
+// (e.g. loaded from env_config), the fields that are specified override the
+// corresponding settings of a copy of base.
+func parseTLS[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+	},
+](conf ParsedConfig, base *tls.Config, hostPort string, logger log.Logger, metrics client.MetricsHandler) (cfg *tls.Config, err error) {
+	var enabled bool
+	if conf.Contains("tls", "enabled") {
+		if enabled, err = conf.FieldBool("tls", "enabled"); err != nil || !enabled {
+			return nil, err
+		}
+	}
+	cfg = &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	r := newTLSReloader(logger, metrics)
+
+	if caFile, _ := conf.FieldString("tls", "ca_file"); caFile != "" {
+		if conf.Contains("tls", "ca_data") {
+			return nil, errors.New("cannot specify both ca_data and ca_file")
+		}
+		r.caFile = caFile
+		cfg.RootCAs = nil
+	} else if caData, _ := conf.FieldString("tls", "ca_data"); caData != "" {
+		if cfg.RootCAs, err = newCertPool([]byte(caData)); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("tls", "cert_file") && conf.Contains("tls", "key_file") {
+		r.certFile, _ = conf.FieldString("tls", "cert_file")
+		r.keyFile, _ = conf.FieldString("tls", "key_file")
+		cfg.Certificates = nil
+	} else if conf.Contains("tls", "cert_data") && conf.Contains("tls", "key_data") {
+		certData, _ := conf.FieldString("tls", "cert_data")
+		keyData, _ := conf.FieldString("tls", "key_data")
+		clientCert, err := tls.X509KeyPair([]byte(certData), []byte(keyData))
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{clientCert}
+	}
+	if conf.Contains("tls", "disable_host_verification") {
+		if cfg.InsecureSkipVerify, err = conf.FieldBool("tls", "disable_host_verification"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("tls", "server_name") {
+		if cfg.ServerName, err = conf.FieldString("tls", "server_name"); err != nil {
+			return nil, err
+		}
+	}
+	if r.caFile != "" || r.certFile != "" {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+		r.apply(cfg, hostPort)
+		return cfg, nil
+	}
+	if enabled || base != nil || len(cfg.Certificates) > 0 || cfg.InsecureSkipVerify || cfg.RootCAs != nil || cfg.ServerName != "" {
+		return cfg, nil
+	}
+	return nil, nil
+}
+
+// setClientTelemetry configures the Temporal client to log and record SDK
+// metrics using the component resources.
+func setClientTelemetry[
+	Resources interface {
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](opts *client.Options, mgr Resources) {
+	opts.Logger = newSDKLogger(mgr.Logger())
+	opts.MetricsHandler = newSDKMetricsHandler[Metrics, Counter, Gauge, Timer](mgr.Metrics())
+}
@@ -0,0 +1,248 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+var errClientPoolClosed = errors.New("client pool is closed")
+
+type (
+	// clientPool lazily dials and caches the clients of a component whose
+	// address or namespace is interpolated, maintaining a client per address
+	// and namespace. Clients that have not been used within the idle timeout
+	// are closed, and the least recently used idle client is closed when the
+	// pool is full.
+	clientPool struct {
+		clients     map[clientPoolKey]*pooledClient
+		closed      bool
+		idleTimeout time.Duration
+		maxClients  int
+		mu          sync.Mutex
+		opts        client.Options
+		start       sync.Once
+		stop        chan struct{}
+	}
+
+	clientPoolKey struct {
+		address   string
+		namespace string
+	}
+
+	// pooledClient is a client managed by a clientPool, which is marked
+	// unhealthy when a request fails because the cluster is unavailable, and
+	// checked before it is used again.
+	pooledClient struct {
+		client   client.Client
+		closed   bool
+		healthy  atomic.Bool
+		key      clientPoolKey
+		lastUsed time.Time
+		mu       sync.Mutex
+		refs     int
+	}
+)
+
+func newClientPool(maxClients int, idleTimeout time.Duration) *clientPool {
+	return &clientPool{
+		clients:     make(map[clientPoolKey]*pooledClient),
+		idleTimeout: idleTimeout,
+		maxClients:  maxClients,
+		stop:        make(chan struct{}),
+	}
+}
+
+// acquire returns the client for key, dialing it if necessary, or an error if
+// the pool is closed. The returned client must be released once it is no
+// longer in use.
+func (p *clientPool) acquire(ctx context.Context, key clientPoolKey) (*pooledClient, error) {
+	p.start.Do(func() {
+		if p.idleTimeout > 0 {
+			go p.evictIdle()
+		}
+	})
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errClientPoolClosed
+	}
+	c, ok := p.clients[key]
+	if !ok {
+		if len(p.clients) >= p.maxClients && !p.evictLRU() {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("client pool is full, all %d clients are in use", p.maxClients)
+		}
+		c = &pooledClient{key: key}
+		c.healthy.Store(true)
+		p.clients[key] = c
+		p.recordSize()
+	}
+	c.refs++
+	c.lastUsed = time.Now()
+	p.mu.Unlock()
+
+	if err := c.ensure(ctx, p.opts); err != nil {
+		p.release(c, err)
+		return nil, err
+	}
+	return c, nil
+}
+
+// release records that a client returned by acquire is no longer in use,
+// marking it unhealthy if err indicates that the cluster is unavailable.
+func (p *clientPool) release(c *pooledClient, err error) {
+	if isUnavailable(err) {
+		c.healthy.Store(false)
+	}
+	p.recordHealth(c)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c.refs--
+	c.lastUsed = time.Now()
+}
+
+// close stops idle eviction and closes all clients, after which acquire
+// fails.
+func (p *clientPool) close() {
+	p.start.Do(func() {})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+	for key, c := range p.clients {
+		c.close()
+		delete(p.clients, key)
+	}
+	p.recordSize()
+}
+
+// evictIdle periodically closes clients that have not been used within the
+// idle timeout.
+func (p *clientPool) evictIdle() {
+	ticker := time.NewTicker(max(p.idleTimeout/2, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			for key, c := range p.clients {
+				if c.refs == 0 && now.Sub(c.lastUsed) >= p.idleTimeout {
+					c.close()
+					delete(p.clients, key)
+				}
+			}
+			p.recordSize()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// evictLRU closes the least recently used idle client, reporting whether a
+// client was evicted.
+func (p *clientPool) evictLRU() bool {
+	var lru *pooledClient
+	for _, c := range p.clients {
+		if c.refs == 0 && (lru == nil || c.lastUsed.Before(lru.lastUsed)) {
+			lru = c
+		}
+	}
+	if lru == nil {
+		return false
+	}
+	lru.close()
+	delete(p.clients, lru.key)
+	return true
+}
+
+func (p *clientPool) recordHealth(c *pooledClient) {
+	if p.opts.MetricsHandler == nil {
+		return
+	}
+	var healthy float64
+	if c.healthy.Load() {
+		healthy = 1
+	}
+	p.opts.MetricsHandler.WithTags(map[string]string{
+		"address":   c.key.address,
+		"namespace": c.key.namespace,
+	}).Gauge("temporal_client_pool_healthy").Update(healthy)
+}
+
+func (p *clientPool) recordSize() {
+	if p.opts.MetricsHandler == nil {
+		return
+	}
+	p.opts.MetricsHandler.Gauge("temporal_client_pool_size").Update(float64(len(p.clients)))
+}
+
+// ensure dials the client if it is not connected, or checks the health of an
+// unhealthy client before it is used again. A client closed by the pool while
+// it was being acquired is not dialed.
+func (c *pooledClient) ensure(ctx context.Context, opts client.Options) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClientPoolClosed
+	}
+	if c.client == nil {
+		opts.HostPort, opts.Namespace = c.key.address, c.key.namespace
+		if c.client, err = client.DialContext(ctx, opts); err != nil {
+			c.healthy.Store(false)
+			return fmt.Errorf("error connecting to Temporal namespace %s: %w", c.key.namespace, err)
+		}
+		c.healthy.Store(true)
+		return nil
+	}
+	if c.healthy.Load() {
+		return nil
+	}
+	if _, err := c.client.CheckHealth(ctx, &client.CheckHealthRequest{}); err != nil {
+		return fmt.Errorf("client for Temporal namespace %s is unhealthy: %w", c.key.namespace, err)
+	}
+	c.healthy.Store(true)
+	return nil
+}
+
+func (c *pooledClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+}
+
+// isUnavailable reports whether err indicates that the Temporal cluster could
+// not be reached.
+func isUnavailable(err error) bool {
+	var unavailable *serviceerror.Unavailable
+	return errors.As(err, &unavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isInterpolated reports whether an interpolated string field contains
+// interpolation functions, falling back to inspecting the raw value for
+// implementations that cannot report it.
+func isInterpolated(s any, raw string) bool {
+	if st, ok := s.(interface{ Static() (string, bool) }); ok {
+		_, static := st.Static()
+		return !static
+	}
+	return strings.Contains(raw, "${!")
+}
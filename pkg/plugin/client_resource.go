@@ -0,0 +1,337 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	ClientResourceType = "temporal_client"
+)
+
+var (
+	// clientResources indexes the active temporal_client resources by id.
+	clientResources sync.Map
+
+	// clientResourceSeq generates temporal_client resource ids.
+	clientResourceSeq atomic.Uint64
+
+	errClientResourceReadOnly = errors.New("temporal_client resources do not support writes")
+)
+
+// ClientResource is a temporal_client resource, which holds the connection
+// settings of a Temporal client whose connection is shared by the components
+// that reference it using client_resource. It is registered as a cache
+// resource, where reading any key returns an id that components use to locate
+// the resource.
+//
+// The underlying connection is dialed when the first component connects and
+// closed when the last component disconnects.
+type ClientResource struct {
+	client client.Client
+	id     string
+	mu     sync.Mutex
+	opts   client.Options
+	refs   int
+}
+
+func NewClientResourceConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Declares a Temporal client whose connection is shared by the components that reference it using client_resource.").
+		Fields(newConnectionConfigFields[Field](fields, false)...)
+}
+
+func NewClientResource[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources) (r *ClientResource, err error) {
+	r = &ClientResource{
+		id: strconv.FormatUint(clientResourceSeq.Add(1), 10),
+	}
+	setClientTelemetry(&r.opts, mgr)
+	if r.opts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, r.opts, nil); err != nil {
+		return nil, err
+	}
+	clientResources.Store(r.id, r)
+	return r, nil
+}
+
+// Get returns the id of the resource, regardless of key.
+func (r *ClientResource) Get(ctx context.Context, key string) ([]byte, error) {
+	return []byte(r.id), nil
+}
+
+func (r *ClientResource) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errClientResourceReadOnly
+}
+
+func (r *ClientResource) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errClientResourceReadOnly
+}
+
+func (r *ClientResource) Delete(ctx context.Context, key string) error {
+	return errClientResourceReadOnly
+}
+
+// Close removes the resource, closing its client if it is still connected.
+// Components that are still connected keep the shared connection open until
+// they are closed.
+func (r *ClientResource) Close(ctx context.Context) error {
+	clientResources.Delete(r.id)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+	return nil
+}
+
+// newClient returns a client that shares the resource's connection, using
+// the connection settings, data converter and telemetry of the resource and
+// the interceptors and context propagators of opts.
+func (r *ClientResource) newClient(ctx context.Context, opts client.Options) (client.Client, client.Options, error) {
+	shared := r.opts
+	shared.ContextPropagators = opts.ContextPropagators
+	shared.Interceptors = opts.Interceptors
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		c, err := client.DialContext(ctx, r.opts)
+		if err != nil {
+			return nil, shared, err
+		}
+		r.client = c
+	}
+	c, err := client.NewClientFromExistingWithContext(ctx, r.client, shared)
+	if err != nil {
+		r.closeIfUnused()
+		return nil, shared, err
+	}
+	r.refs++
+	return c, shared, nil
+}
+
+// release records that a client returned by newClient was closed, closing the
+// connection when no clients remain.
+func (r *ClientResource) release() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refs--
+	r.closeIfUnused()
+}
+
+func (r *ClientResource) closeIfUnused() {
+	if r.refs == 0 && r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+}
+
+// clientConn manages the Temporal client of a component, which either dials
+// its own connection, shares the connection of a temporal_client resource, or
+// maintains a pool of clients when the address or namespace is interpolated.
+type clientConn struct {
+	client          client.Client
+	clientOpts      client.Options
+	clientResource  string
+	mu              sync.Mutex
+	pool            *clientPool
+	resolveResource func(context.Context) (*ClientResource, error)
+	resource        *ClientResource
+}
+
+// initClientConn parses the client_resource field, which cannot be combined
+// with the connection fields, including namespace and codec_auth.
+func initClientConn[
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+](c *clientConn, conf ParsedConfig, mgr Resources) (err error) {
+	if !conf.Contains("client_resource") {
+		return nil
+	}
+	if c.clientResource, err = conf.FieldString("client_resource"); err != nil {
+		return err
+	}
+	if c.pool != nil {
+		return errors.New("cannot specify an interpolated address or namespace with client_resource")
+	}
+	var codecs []string
+	if conf.Contains("codecs") {
+		if codecs, err = conf.FieldStringList("codecs"); err != nil {
+			return err
+		}
+	}
+	if c.clientOpts.HostPort != "" || c.clientOpts.Namespace != "" || c.clientOpts.ConnectionOptions.TLS != nil || c.clientOpts.Credentials != nil || c.clientOpts.HeadersProvider != nil || conf.Contains("codec_auth") || conf.Contains("codec_endpoint") || len(codecs) > 0 || conf.Contains("encryption", "key_id") {
+		return errors.New("cannot specify address, auth, codec_auth, codec_endpoint, codecs, encryption, env_config, namespace or tls with client_resource")
+	}
+	name := c.clientResource
+	c.resolveResource = func(ctx context.Context) (*ClientResource, error) {
+		var id []byte
+		var getErr error
+		if err := mgr.AccessCache(ctx, name, func(cache Cache) {
+			id, getErr = cache.Get(ctx, "")
+		}); err != nil {
+			return nil, fmt.Errorf("error accessing client_resource %s: %w", name, err)
+		}
+		r, ok := clientResources.Load(string(id))
+		if getErr != nil || !ok {
+			return nil, fmt.Errorf("client_resource %s is not a %s resource", name, ClientResourceType)
+		}
+		return r.(*ClientResource), nil
+	}
+	return nil
+}
+
+// connect dials the component's client, or creates a client that shares the
+// connection of the referenced client_resource. Pooled clients are dialed
+// when first used.
+func (c *clientConn) connect(ctx context.Context) (err error) {
+	if c.pool != nil {
+		c.pool.opts = c.clientOpts
+		return nil
+	}
+	if c.clientResource == "" {
+		if c.client, err = client.DialContext(ctx, c.clientOpts); err != nil {
+			return fmt.Errorf("error connecting to Temporal: %w", err)
+		}
+		return nil
+	}
+	r, err := c.resolveResource(ctx)
+	if err != nil {
+		return err
+	}
+	cl, opts, err := r.newClient(ctx, c.clientOpts)
+	if err != nil {
+		return fmt.Errorf("error connecting to Temporal: %w", err)
+	}
+	c.client, c.clientOpts, c.resource = cl, opts, r
+	return nil
+}
+
+// connectLazy initializes a client that connects on first use, for
+// components without a Connect phase. Clients that share a client_resource
+// are connected by ensureConnected instead.
+func (c *clientConn) connectLazy() (err error) {
+	if c.pool != nil {
+		c.pool.opts = c.clientOpts
+		return nil
+	}
+	if c.clientResource != "" {
+		return nil
+	}
+	if c.client, err = client.NewLazyClient(c.clientOpts); err != nil {
+		return fmt.Errorf("error initializing Temporal client: %w", err)
+	}
+	return nil
+}
+
+// ensureConnected connects a client initialized with connectLazy that shares
+// a client_resource, if it is not already connected.
+func (c *clientConn) ensureConnected(ctx context.Context) error {
+	if c.clientResource == "" {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return nil
+	}
+	return c.connect(ctx)
+}
+
+// close closes the client, releasing the shared connection if any.
+func (c *clientConn) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pool != nil {
+		c.pool.close()
+	}
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	if c.resource != nil {
+		c.resource.release()
+		c.resource = nil
+	}
+}
@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/golang/snappy"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/contrib/envconfig"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	codecEncryption = "encryption"
+	codecRemote     = "remote"
+	codecSnappy     = "snappy"
+	codecZlib       = "zlib"
+
+	// metadataEncodingSnappy identifies payloads compressed by the snappy
+	// codec, matching the Temporal samples' snappy codec.
+	metadataEncodingSnappy = "binary/snappy"
+)
+
+// snappyCodec is a payload codec that compresses payloads using snappy,
+// leaving payloads that do not get smaller as is.
+type snappyCodec struct{}
+
+// parseCodecs parses the codecs field into the payload codecs used by the data
+// converter, where the encryption and remote codecs are configured by the
+// encryption field and the resolved codec server settings. When codecs is not
+// specified, any configured remote and encryption codecs are applied in that
+// order.
+func parseCodecs(conf interface {
+	Contains(...string) bool
+	FieldString(...string) (string, error)
+	FieldStringList(...string) ([]string, error)
+	FieldStringMap(...string) (map[string]string, error)
+}, remote envconfig.ClientConfigCodec) ([]converter.PayloadCodec, error) {
+	available := make(map[string]converter.PayloadCodec)
+	encryption, err := parseEncryption(conf)
+	if err != nil {
+		return nil, err
+	}
+	if encryption != nil {
+		available[codecEncryption] = encryption
+	}
+	if remote.Endpoint != "" {
+		codecOpts := converter.RemotePayloadCodecOptions{Endpoint: remote.Endpoint}
+		if codecAuth := remote.Auth; codecAuth != "" {
+			codecOpts.ModifyRequest = func(r *http.Request) error {
+				r.Header.Set("Authorization", codecAuth)
+				return nil
+			}
+		}
+		available[codecRemote] = converter.NewRemotePayloadCodec(codecOpts)
+	}
+
+	var names []string
+	if conf.Contains("codecs") {
+		if names, err = conf.FieldStringList("codecs"); err != nil {
+			return nil, err
+		}
+	}
+	explicit := len(names) > 0
+	if !explicit {
+		names = []string{codecRemote, codecEncryption}
+	} else {
+		for name := range available {
+			if !slices.Contains(names, name) {
+				return nil, fmt.Errorf("%s codec is configured but not listed in codecs", name)
+			}
+		}
+	}
+
+	var codecs []converter.PayloadCodec
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate codecs[%d]: %s", i, name)
+		}
+		seen[name] = true
+		switch name {
+		case codecEncryption, codecRemote:
+			codec, ok := available[name]
+			if !ok && explicit {
+				if name == codecEncryption {
+					return nil, errors.New("encryption codec requires encryption.key_id")
+				}
+				return nil, errors.New("remote codec requires codec_endpoint")
+			}
+			if ok {
+				codecs = append(codecs, codec)
+			}
+		case codecSnappy:
+			codecs = append(codecs, snappyCodec{})
+		case codecZlib:
+			codecs = append(codecs, converter.NewZlibCodec(converter.ZlibCodecOptions{}))
+		default:
+			return nil, fmt.Errorf("invalid codecs[%d]: %s, must be one of %s, %s, %s, or %s", i, name, codecEncryption, codecRemote, codecSnappy, codecZlib)
+		}
+	}
+	// the data converter applies codecs last to first when encoding
+	slices.Reverse(codecs)
+	return codecs, nil
+}
+
+// Encode compresses each payload that gets smaller when compressed.
+func (snappyCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return payloads, err
+		}
+		compressed := snappy.Encode(nil, b)
+		if len(compressed) >= len(b) {
+			result[i] = p
+			continue
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{converter.MetadataEncoding: []byte(metadataEncodingSnappy)},
+			Data:     compressed,
+		}
+	}
+	return result, nil
+}
+
+// Decode decompresses each compressed payload, leaving other payloads as is.
+func (snappyCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.GetMetadata()[converter.MetadataEncoding]) != metadataEncodingSnappy {
+			result[i] = p
+			continue
+		}
+		b, err := snappy.Decode(nil, p.GetData())
+		if err != nil {
+			return payloads, fmt.Errorf("error decompressing payload: %w", err)
+		}
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
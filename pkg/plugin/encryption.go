@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// metadataEncodingEncrypted identifies payloads encrypted by the
+	// encryption codec, matching the Temporal samples' encryption codec.
+	metadataEncodingEncrypted = "binary/encrypted"
+
+	// metadataEncryptionKeyID holds the id of the key used to encrypt a
+	// payload.
+	metadataEncryptionKeyID = "encryption-key-id"
+)
+
+// encryptionCodec is a payload codec that encrypts payloads using AES-256-GCM,
+// compatible with the Temporal samples' encryption codec. Payloads are
+// encrypted with the active key, whose id is stored in the payload metadata
+// so that payloads encrypted with previous keys can still be decrypted.
+type encryptionCodec struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// newEncryptionConfigField returns the encryption field used to configure
+// the encryption codec.
+func newEncryptionConfigField[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewObjectField(string, ...Field) Field
+		NewStringField(string) Field
+		NewStringMapField(string) Field
+	},
+](fields FieldProvider) Field {
+	return fields.NewObjectField("encryption",
+		fields.NewStringMapField("key_files").
+			Description("Paths to files containing encryption keys, indexed by key id").
+			Optional(),
+		fields.NewStringField("key_id").
+			Description("Id of the key used to encrypt payloads, which enables the encryption codec").
+			Optional(),
+		fields.NewStringMapField("keys").
+			Description("Encryption keys indexed by key id (e.g. ${ENCRYPTION_KEY}), where keys other than key_id are used to decrypt payloads encrypted before a key rotation").
+			Optional(),
+	).
+		Description("Encrypts payloads using AES-256-GCM with 32-byte keys that are hex encoded, base64 encoded, or raw, compatible with the Temporal samples' encryption codec").
+		Optional()
+}
+
+// parseEncryption parses the encryption field into an encryption codec,
+// returning nil if it is not configured.
+func parseEncryption(conf interface {
+	Contains(...string) bool
+	FieldString(...string) (string, error)
+	FieldStringMap(...string) (map[string]string, error)
+}) (converter.PayloadCodec, error) {
+	keys := make(map[string][]byte)
+	if conf.Contains("encryption", "keys") {
+		values, err := conf.FieldStringMap("encryption", "keys")
+		if err != nil {
+			return nil, err
+		}
+		for id, v := range values {
+			keys[id] = []byte(v)
+		}
+	}
+	if conf.Contains("encryption", "key_files") {
+		paths, err := conf.FieldStringMap("encryption", "key_files")
+		if err != nil {
+			return nil, err
+		}
+		for id, path := range paths {
+			if _, ok := keys[id]; ok {
+				return nil, fmt.Errorf("cannot specify encryption key %s in both encryption.keys and encryption.key_files", id)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading encryption key %s: %w", id, err)
+			}
+			keys[id] = b
+		}
+	}
+	if !conf.Contains("encryption", "key_id") {
+		if len(keys) > 0 {
+			return nil, errors.New("encryption.key_id is required when encryption keys are specified")
+		}
+		return nil, nil
+	}
+	keyID, err := conf.FieldString("encryption", "key_id")
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptionCodec(keyID, keys)
+}
+
+// newEncryptionCodec returns an encryption codec that encrypts payloads using
+// the key identified by keyID.
+func newEncryptionCodec(keyID string, keys map[string][]byte) (*encryptionCodec, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, fmt.Errorf("encryption key %s not found in encryption.keys or encryption.key_files", keyID)
+	}
+	c := &encryptionCodec{
+		keyID: keyID,
+		keys:  make(map[string]cipher.AEAD, len(keys)),
+	}
+	for id, b := range keys {
+		key, err := decodeEncryptionKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %w", id, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %w", id, err)
+		}
+		if c.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %w", id, err)
+		}
+	}
+	return c, nil
+}
+
+// Encode encrypts each payload with the active key.
+func (c *encryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	aead := c.keys[c.keyID]
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		b, err := proto.Marshal(p)
+		if err != nil {
+			return payloads, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return payloads, err
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(metadataEncodingEncrypted),
+				metadataEncryptionKeyID:    []byte(c.keyID),
+			},
+			Data: aead.Seal(nonce, nonce, b, nil),
+		}
+	}
+	return result, nil
+}
+
+// Decode decrypts each encrypted payload with the key identified in its
+// metadata, leaving other payloads as is.
+func (c *encryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.GetMetadata()[converter.MetadataEncoding]) != metadataEncodingEncrypted {
+			result[i] = p
+			continue
+		}
+		keyID, ok := p.GetMetadata()[metadataEncryptionKeyID]
+		if !ok {
+			return payloads, errors.New("encrypted payload is missing the encryption key id")
+		}
+		aead, ok := c.keys[string(keyID)]
+		if !ok {
+			return payloads, fmt.Errorf("encryption key %s not found", keyID)
+		}
+		data := p.GetData()
+		if len(data) < aead.NonceSize() {
+			return payloads, errors.New("encrypted payload is too short")
+		}
+		b, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+		if err != nil {
+			return payloads, fmt.Errorf("error decrypting payload with encryption key %s: %w", keyID, err)
+		}
+		result[i] = &commonpb.Payload{}
+		if err := proto.Unmarshal(b, result[i]); err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
+
+// decodeEncryptionKey decodes a 32-byte key that is hex encoded, base64
+// encoded, or raw.
+func decodeEncryptionKey(b []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 64 {
+		if key, err := hex.DecodeString(string(trimmed)); err == nil {
+			return key, nil
+		}
+	}
+	if key, err := base64.StdEncoding.DecodeString(string(trimmed)); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if len(b) == 32 {
+		return slices.Clone(b), nil
+	}
+	if len(trimmed) == 32 {
+		return slices.Clone(trimmed), nil
+	}
+	return nil, errors.New("must be a 32-byte key that is hex encoded, base64 encoded, or raw")
+}
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+
+	"go.temporal.io/sdk/contrib/envconfig"
+)
+
+// newEnvConfigConfigField returns the env_config field used to load connection
+// settings from a Temporal CLI environment configuration profile.
+func newEnvConfigConfigField[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewObjectField(string, ...Field) Field
+		NewStringField(string) Field
+	},
+](fields FieldProvider) Field {
+	return fields.NewObjectField("env_config",
+		fields.NewStringField("config_file").
+			Description("Path to the configuration file, defaults to TEMPORAL_CONFIG_FILE or the temporal CLI default (e.g. ~/.config/temporalio/temporal.toml)").
+			Optional(),
+		fields.NewBoolField("enabled").
+			Description("Loads the connection settings from the environment configuration, which defaults to true if profile or config_file are specified").
+			Optional(),
+		fields.NewStringField("profile").
+			Description("Name of the configuration profile, defaults to TEMPORAL_PROFILE or default").
+			Optional(),
+	).
+		Description("Loads the address, namespace, TLS, API key, gRPC metadata, and codec settings from a Temporal CLI environment configuration profile and TEMPORAL_* environment variables, where explicitly configured fields take precedence (e.g. tls.server_name overrides only the server name of the profile's TLS settings)").
+		Optional()
+}
+
+// loadEnvConfig loads the profile configured by the env_config field, returning
+// nil if it is not enabled.
+func loadEnvConfig(conf interface {
+	Contains(...string) bool
+	FieldBool(...string) (bool, error)
+	FieldString(...string) (string, error)
+}) (*envconfig.ClientConfigProfile, error) {
+	enabled := conf.Contains("env_config", "config_file") || conf.Contains("env_config", "profile")
+	if conf.Contains("env_config", "enabled") {
+		var err error
+		if enabled, err = conf.FieldBool("env_config", "enabled"); err != nil {
+			return nil, err
+		}
+	}
+	if !enabled {
+		return nil, nil
+	}
+
+	var opts envconfig.LoadClientConfigProfileOptions
+	if conf.Contains("env_config", "config_file") {
+		var err error
+		if opts.ConfigFilePath, err = conf.FieldString("env_config", "config_file"); err != nil {
+			return nil, err
+		}
+		// the profile loader ignores missing files, which is only desirable
+		// for the default file
+		if _, err := os.Stat(opts.ConfigFilePath); err != nil {
+			return nil, fmt.Errorf("error loading env_config: %w", err)
+		}
+	}
+	if conf.Contains("env_config", "profile") {
+		var err error
+		if opts.ConfigFileProfile, err = conf.FieldString("env_config", "profile"); err != nil {
+			return nil, err
+		}
+	}
+	profile, err := envconfig.LoadClientConfigProfile(opts)
+	if err != nil {
+		return nil, fmt.Errorf("error loading env_config: %w", err)
+	}
+	return &profile, nil
+}
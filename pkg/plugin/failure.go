@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// workflowFailure describes a workflow execution that completed unsuccessfully,
+// as opposed to an error communicating with the Temporal cluster.
+type workflowFailure struct {
+	Message      string
+	NonRetryable bool
+	Type         string
+}
+
+// workflowFailureError is returned by the temporal_workflow output when a
+// workflow execution completes unsuccessfully. Its message is a JSON object
+// describing the failure, so that a fallback output can parse it from the
+// fallback_error metadata.
+type workflowFailureError struct {
+	err        error
+	failure    workflowFailure
+	runID      string
+	workflowID string
+}
+
+// newWorkflowFailure returns the failure details of a workflow execution
+// error, or false if err is not a workflow failure (e.g. a transport error).
+func newWorkflowFailure(err error) (f workflowFailure, ok bool) {
+	var execErr *temporal.WorkflowExecutionError
+	if !errors.As(err, &execErr) {
+		return f, false
+	}
+	cause := errors.Unwrap(execErr)
+	if cause == nil {
+		return workflowFailure{Message: execErr.Error(), Type: "WorkflowExecutionError"}, true
+	}
+	f.Message = cause.Error()
+	var (
+		appErr        *temporal.ApplicationError
+		canceledErr   *temporal.CanceledError
+		panicErr      *temporal.PanicError
+		terminatedErr *temporal.TerminatedError
+		timeoutErr    *temporal.TimeoutError
+	)
+	switch {
+	case errors.As(cause, &appErr):
+		f.NonRetryable, f.Type = appErr.NonRetryable(), appErr.Type()
+		if msg := appErr.Message(); msg != "" {
+			f.Message = msg
+		}
+	case errors.As(cause, &canceledErr):
+		f.Type = "CanceledError"
+	case errors.As(cause, &panicErr):
+		f.Type = "PanicError"
+	case errors.As(cause, &terminatedErr):
+		f.Type = "TerminatedError"
+	case errors.As(cause, &timeoutErr):
+		f.Type = "TimeoutError"
+	default:
+		f.Type = fmt.Sprintf("%T", cause)
+	}
+	return f, true
+}
+
+// setMetadata records the failure details on msg.
+func (f workflowFailure) setMetadata(msg interface{ MetaSet(string, string) }) {
+	msg.MetaSet("workflow_failure_message", f.Message)
+	msg.MetaSet("workflow_failure_non_retryable", strconv.FormatBool(f.NonRetryable))
+	msg.MetaSet("workflow_failure_type", f.Type)
+}
+
+// Error returns the failure details as a JSON object, using the same keys as the
+// failure metadata set by the temporal_workflow processor.
+func (e *workflowFailureError) Error() string {
+	b, err := json.Marshal(struct {
+		WorkflowID   string `json:"workflow_id"`
+		RunID        string `json:"run_id"`
+		Message      string `json:"workflow_failure_message"`
+		NonRetryable bool   `json:"workflow_failure_non_retryable"`
+		Type         string `json:"workflow_failure_type"`
+	}{e.workflowID, e.runID, e.failure.Message, e.failure.NonRetryable, e.failure.Type})
+	if err != nil {
+		return fmt.Sprintf("workflow %s failed: %v", e.workflowID, e.err)
+	}
+	return string(b)
+}
+
+// Unwrap returns the workflow execution error.
+func (e *workflowFailureError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,62 @@
+package plugin
+
+import (
+	"context"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/interceptor"
+)
+
+type (
+	// headersContextKey identifies the encoded headers attached to a start
+	// request context.
+	headersContextKey struct{}
+
+	// headersInterceptor copies encoded headers from the request context onto
+	// outbound workflow start requests.
+	headersInterceptor struct {
+		interceptor.ClientInterceptorBase
+	}
+
+	headersOutboundInterceptor struct {
+		interceptor.ClientOutboundInterceptorBase
+	}
+)
+
+// withHeaders returns a copy of ctx carrying the given encoded headers.
+func withHeaders(ctx context.Context, headers map[string]*commonpb.Payload) context.Context {
+	return context.WithValue(ctx, headersContextKey{}, headers)
+}
+
+// setHeaders copies any encoded headers attached to ctx onto the outbound
+// request header.
+func setHeaders(ctx context.Context) {
+	headers, _ := ctx.Value(headersContextKey{}).(map[string]*commonpb.Payload)
+	if len(headers) == 0 {
+		return
+	}
+	header := interceptor.Header(ctx)
+	if header == nil {
+		return
+	}
+	for k, v := range headers {
+		header[k] = v
+	}
+}
+
+func (i *headersInterceptor) InterceptClient(next interceptor.ClientOutboundInterceptor) interceptor.ClientOutboundInterceptor {
+	o := &headersOutboundInterceptor{}
+	o.Next = next
+	return o
+}
+
+func (o *headersOutboundInterceptor) ExecuteWorkflow(ctx context.Context, in *interceptor.ClientExecuteWorkflowInput) (client.WorkflowRun, error) {
+	setHeaders(ctx)
+	return o.Next.ExecuteWorkflow(ctx, in)
+}
+
+func (o *headersOutboundInterceptor) SignalWithStartWorkflow(ctx context.Context, in *interceptor.ClientSignalWithStartWorkflowInput) (client.WorkflowRun, error) {
+	setHeaders(ctx)
+	return o.Next.SignalWithStartWorkflow(ctx, in)
+}
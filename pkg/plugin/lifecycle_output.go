@@ -0,0 +1,251 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	LifecycleOutputType = "temporal_lifecycle"
+)
+
+type (
+	LifecycleOutput[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+		},
+	] struct {
+		clientConn
+		dc             converter.DataConverter
+		details        Mapping
+		detailsExists  bool
+		ignoreNotFound bool
+		operation      string
+		reason         InterpolatedString
+		reasonExists   bool
+		runID          InterpolatedString
+		runIDExists    bool
+		workflowID     InterpolatedString
+	}
+
+	LifecycleOutputOptions[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+		},
+	] func(*LifecycleOutput[InterpolatedString, Mapping, Message]) error
+)
+
+func NewLifecycleOutputConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Cancels or terminates a Temporal workflow for each message as input.").
+		Fields(newClientConfigFields[Field](fields, false)...).
+		Fields(
+			fields.NewBloblangField("details").
+				Description("Termination details mapping, ignored when cancelling").
+				Optional(),
+			fields.NewBoolField("ignore_not_found").
+				Description("Acknowledges messages targeting workflows that do not exist or are already closed").
+				Default(true),
+			fields.NewIntField("max_in_flight").
+				Description("Maximum number of pending requests").
+				Default(64),
+			fields.NewStringEnumField("operation", "cancel", "terminate").
+				Description("Requests cancellation of, or terminates, the workflow execution").
+				Default("cancel"),
+			fields.NewInterpolatedStringField("reason").
+				Description("Termination reason, ignored when cancelling").
+				Optional(),
+			fields.NewInterpolatedStringField("run_id").
+				Description("Workflow run ID, defaults to the current run").
+				Optional(),
+			fields.NewInterpolatedStringField("workflow_id").
+				Description("Workflow ID"),
+		)
+}
+
+func NewLifecycleOutput[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, opts ...LifecycleOutputOptions[InterpolatedString, Mapping, Message]) (o *LifecycleOutput[InterpolatedString, Mapping, Message], maxInFlight int, err error) {
+	o = &LifecycleOutput[InterpolatedString, Mapping, Message]{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, 0, err
+		}
+	}
+	setClientTelemetry(&o.clientOpts, mgr)
+	if o.clientOpts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, o.clientOpts, o.dc); err != nil {
+		return nil, 0, err
+	}
+	if err := initClientConn(&o.clientConn, conf, mgr); err != nil {
+		return nil, 0, err
+	}
+	if conf.Contains("details") {
+		o.detailsExists = true
+		if o.details, err = conf.FieldBloblang("details"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if o.ignoreNotFound, err = conf.FieldBool("ignore_not_found"); err != nil {
+		return nil, 0, err
+	}
+	if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+		return nil, 0, err
+	}
+	if o.operation, err = conf.FieldString("operation"); err != nil {
+		return nil, 0, err
+	}
+	if conf.Contains("reason") {
+		o.reasonExists = true
+		if o.reason, err = conf.FieldInterpolatedString("reason"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if conf.Contains("run_id") {
+		o.runIDExists = true
+		if o.runID, err = conf.FieldInterpolatedString("run_id"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if o.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
+		return nil, 0, err
+	}
+	return o, maxInFlight, nil
+}
+
+func (o *LifecycleOutput[InterpolatedString, Mapping, Message]) Close(ctx context.Context) error {
+	o.close()
+	return nil
+}
+
+func (o *LifecycleOutput[InterpolatedString, Mapping, Message]) Connect(ctx context.Context) error {
+	return o.connect(ctx)
+}
+
+func (o *LifecycleOutput[InterpolatedString, Mapping, Message]) Write(ctx context.Context, msg Message) (err error) {
+	workflowID, err := o.workflowID.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("error evaluating workflow_id: %w", err)
+	}
+	var runID string
+	if o.runIDExists {
+		if runID, err = o.runID.TryString(msg); err != nil {
+			return fmt.Errorf("error evaluating run_id: %w", err)
+		}
+	}
+	switch o.operation {
+	case "cancel":
+		err = o.client.CancelWorkflow(ctx, workflowID, runID)
+	case "terminate":
+		var reason string
+		if o.reasonExists {
+			if reason, err = o.reason.TryString(msg); err != nil {
+				return fmt.Errorf("error evaluating reason: %w", err)
+			}
+		}
+		var details []any
+		if o.detailsExists {
+			d, err := msg.BloblangQuery(o.details)
+			if err != nil {
+				return fmt.Errorf("error evaluating details: %w", err)
+			}
+			if details, err = newArgs[Mapping](d); err != nil {
+				return err
+			}
+		}
+		err = o.client.TerminateWorkflow(ctx, workflowID, runID, reason, details...)
+	default:
+		return fmt.Errorf("invalid operation: %s", o.operation)
+	}
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		if o.ignoreNotFound && errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("error executing %s operation: %w", o.operation, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"go.temporal.io/sdk/log"
+)
+
+// sdkLogger adapts a component logger to the Temporal SDK logger interface.
+type sdkLogger[
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+] struct {
+	logger Logger
+}
+
+// newSDKLogger returns a Temporal SDK logger that writes to logger.
+func newSDKLogger[
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+](logger Logger) log.Logger {
+	return &sdkLogger[Logger]{logger: logger}
+}
+
+func (l *sdkLogger[Logger]) Debug(msg string, keyvals ...any) {
+	l.with(keyvals).Debug(msg)
+}
+
+func (l *sdkLogger[Logger]) Error(msg string, keyvals ...any) {
+	l.with(keyvals).Error(msg)
+}
+
+func (l *sdkLogger[Logger]) Info(msg string, keyvals ...any) {
+	l.with(keyvals).Info(msg)
+}
+
+func (l *sdkLogger[Logger]) Warn(msg string, keyvals ...any) {
+	l.with(keyvals).Warn(msg)
+}
+
+// With returns a child logger that includes keyvals in every log entry.
+func (l *sdkLogger[Logger]) With(keyvals ...any) log.Logger {
+	return &sdkLogger[Logger]{logger: l.with(keyvals)}
+}
+
+func (l *sdkLogger[Logger]) with(keyvals []any) Logger {
+	if len(keyvals) == 0 {
+		return l.logger
+	}
+	return l.logger.With(keyvals...)
+}
@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/workflow"
+)
+
+type (
+	// metadataContextKey identifies the propagated metadata attached to a
+	// context.
+	metadataContextKey struct{}
+
+	// metadataFilter selects the message metadata propagated as Temporal
+	// headers.
+	metadataFilter struct {
+		excludePatterns []*regexp.Regexp
+		excludePrefixes []string
+		includePatterns []*regexp.Regexp
+		includePrefixes []string
+	}
+
+	// MetadataPropagator is a workflow.ContextPropagator that propagates
+	// message metadata as Temporal headers, where each metadata key is encoded
+	// as a separate string header using the default data converter.
+	MetadataPropagator struct {
+		keys []string
+	}
+)
+
+// newPropagateMetadataConfigField returns the propagate_metadata field shared
+// by the components that support metadata propagation.
+func newPropagateMetadataConfigField[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewObjectField(string, ...Field) Field
+		NewStringListField(string) Field
+	},
+](fields FieldProvider) Field {
+	return fields.NewObjectField("propagate_metadata",
+		fields.NewStringListField("exclude_patterns").
+			Description("Regular expressions matching metadata keys to exclude").
+			Optional(),
+		fields.NewStringListField("exclude_prefixes").
+			Description("Prefixes of metadata keys to exclude").
+			Optional(),
+		fields.NewStringListField("include_patterns").
+			Description("Regular expressions matching metadata keys to include, where all keys that are not excluded are included if only exclude rules are specified").
+			Optional(),
+		fields.NewStringListField("include_prefixes").
+			Description("Prefixes of metadata keys to include, where all keys that are not excluded are included if only exclude rules are specified").
+			Optional(),
+	).
+		Description("Propagates matching message metadata as Temporal headers, using a context propagator, where nothing is propagated unless at least one rule is specified (e.g. include_patterns: [\".*\"] propagates all metadata)").
+		Optional()
+}
+
+// parseMetadataFilter parses the propagate_metadata field, returning nil if no
+// rules are specified, as an empty propagate_metadata object cannot be
+// distinguished from an omitted one.
+func parseMetadataFilter(conf interface {
+	FieldStringList(...string) ([]string, error)
+}) (*metadataFilter, error) {
+	lists := make(map[string][]string, 4)
+	for _, name := range []string{"exclude_patterns", "exclude_prefixes", "include_patterns", "include_prefixes"} {
+		list, err := conf.FieldStringList("propagate_metadata", name)
+		if err != nil {
+			return nil, err
+		}
+		lists[name] = list
+	}
+	f := &metadataFilter{
+		excludePrefixes: lists["exclude_prefixes"],
+		includePrefixes: lists["include_prefixes"],
+	}
+	var err error
+	if f.excludePatterns, err = compilePatterns("exclude_patterns", lists["exclude_patterns"]); err != nil {
+		return nil, err
+	}
+	if f.includePatterns, err = compilePatterns("include_patterns", lists["include_patterns"]); err != nil {
+		return nil, err
+	}
+	if len(f.excludePatterns) == 0 && len(f.excludePrefixes) == 0 && len(f.includePatterns) == 0 && len(f.includePrefixes) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func compilePatterns(name string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid propagate_metadata.%s[%d]: %w", name, i, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// match reports whether the metadata key should be propagated.
+func (f *metadataFilter) match(key string) bool {
+	if matchKey(key, f.excludePrefixes, f.excludePatterns) {
+		return false
+	}
+	if len(f.includePrefixes) == 0 && len(f.includePatterns) == 0 {
+		return true
+	}
+	return matchKey(key, f.includePrefixes, f.includePatterns)
+}
+
+func matchKey(key string, prefixes []string, patterns []*regexp.Regexp) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for _, re := range patterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// withMetadata returns a copy of ctx carrying the metadata of msg selected by
+// f, which is encoded as headers by the MetadataPropagator.
+func withMetadata[
+	Message interface {
+		MetaWalk(func(string, string) error) error
+	},
+](ctx context.Context, f *metadataFilter, msg Message) (context.Context, error) {
+	md := make(map[string]string)
+	if err := msg.MetaWalk(func(k, v string) error {
+		if f.match(k) {
+			md[k] = v
+		}
+		return nil
+	}); err != nil {
+		return ctx, fmt.Errorf("error evaluating propagate_metadata: %w", err)
+	}
+	return ContextWithMetadata(ctx, md), nil
+}
+
+// NewMetadataPropagator returns a context propagator that injects the
+// metadata attached to a context as Temporal headers, and extracts the headers
+// with the given keys, allowing workers to access the propagated metadata via
+// MetadataFromContext and MetadataFromWorkflowContext.
+func NewMetadataPropagator(keys ...string) workflow.ContextPropagator {
+	return &MetadataPropagator{keys: keys}
+}
+
+// ContextWithMetadata returns a copy of ctx carrying the given metadata.
+func ContextWithMetadata(ctx context.Context, md map[string]string) context.Context {
+	return context.WithValue(ctx, metadataContextKey{}, md)
+}
+
+// MetadataFromContext returns the metadata attached to ctx.
+func MetadataFromContext(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataContextKey{}).(map[string]string)
+	return md
+}
+
+// MetadataFromWorkflowContext returns the metadata attached to a workflow
+// context.
+func MetadataFromWorkflowContext(ctx workflow.Context) map[string]string {
+	md, _ := ctx.Value(metadataContextKey{}).(map[string]string)
+	return md
+}
+
+func (p *MetadataPropagator) Inject(ctx context.Context, w workflow.HeaderWriter) error {
+	return p.inject(MetadataFromContext(ctx), w)
+}
+
+func (p *MetadataPropagator) InjectFromWorkflow(ctx workflow.Context, w workflow.HeaderWriter) error {
+	return p.inject(MetadataFromWorkflowContext(ctx), w)
+}
+
+func (p *MetadataPropagator) Extract(ctx context.Context, r workflow.HeaderReader) (context.Context, error) {
+	md, err := p.extract(r)
+	if err != nil || md == nil {
+		return ctx, err
+	}
+	return ContextWithMetadata(ctx, md), nil
+}
+
+func (p *MetadataPropagator) ExtractToWorkflow(ctx workflow.Context, r workflow.HeaderReader) (workflow.Context, error) {
+	md, err := p.extract(r)
+	if err != nil || md == nil {
+		return ctx, err
+	}
+	return workflow.WithValue(ctx, metadataContextKey{}, md), nil
+}
+
+func (p *MetadataPropagator) inject(md map[string]string, w workflow.HeaderWriter) error {
+	dc := converter.GetDefaultDataConverter()
+	for k, v := range md {
+		payload, err := dc.ToPayload(v)
+		if err != nil {
+			return fmt.Errorf("error encoding metadata %s: %w", k, err)
+		}
+		w.Set(k, payload)
+	}
+	return nil
+}
+
+func (p *MetadataPropagator) extract(r workflow.HeaderReader) (map[string]string, error) {
+	if len(p.keys) == 0 {
+		return nil, nil
+	}
+	dc := converter.GetDefaultDataConverter()
+	md := make(map[string]string)
+	err := r.ForEachKey(func(k string, payload *commonpb.Payload) error {
+		if !slices.Contains(p.keys, k) {
+			return nil
+		}
+		var v string
+		if err := dc.FromPayload(payload, &v); err != nil {
+			return fmt.Errorf("error decoding metadata %s: %w", k, err)
+		}
+		md[k] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return md, nil
+}
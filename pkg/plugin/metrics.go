@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+type (
+	sdkCounterFunc func(int64)
+	sdkGaugeFunc   func(float64)
+	sdkTimerFunc   func(time.Duration)
+)
+
+func (f sdkCounterFunc) Inc(d int64)          { f(d) }
+func (f sdkGaugeFunc) Update(v float64)       { f(v) }
+func (f sdkTimerFunc) Record(d time.Duration) { f(d) }
+
+// sdkMetricsHandler adapts component metrics to the Temporal SDK metrics
+// handler interface, where SDK tags are mapped to metric labels.
+type sdkMetricsHandler[
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+] struct {
+	cache   *sync.Map
+	keys    []string
+	metrics Metrics
+	values  []string
+}
+
+// newSDKMetricsHandler returns a Temporal SDK metrics handler that records
+// SDK metrics using the provided component metrics.
+func newSDKMetricsHandler[
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](metrics Metrics) client.MetricsHandler {
+	return &sdkMetricsHandler[Metrics, Counter, Gauge, Timer]{
+		cache:   &sync.Map{},
+		metrics: metrics,
+	}
+}
+
+// WithTags returns a handler that includes tags as labels on every metric
+// it creates.
+func (h *sdkMetricsHandler[Metrics, Counter, Gauge, Timer]) WithTags(tags map[string]string) client.MetricsHandler {
+	merged := make(map[string]string, len(h.keys)+len(tags))
+	for i, k := range h.keys {
+		merged[k] = h.values[i]
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = merged[k]
+	}
+	return &sdkMetricsHandler[Metrics, Counter, Gauge, Timer]{
+		cache:   h.cache,
+		keys:    keys,
+		metrics: h.metrics,
+		values:  values,
+	}
+}
+
+func (h *sdkMetricsHandler[Metrics, Counter, Gauge, Timer]) Counter(name string) client.MetricsCounter {
+	c := h.load("counter", name, func() any {
+		return h.metrics.NewCounter(name, h.keys...)
+	}).(Counter)
+	return sdkCounterFunc(func(d int64) {
+		c.Incr(d, h.values...)
+	})
+}
+
+func (h *sdkMetricsHandler[Metrics, Counter, Gauge, Timer]) Gauge(name string) client.MetricsGauge {
+	g := h.load("gauge", name, func() any {
+		return h.metrics.NewGauge(name, h.keys...)
+	}).(Gauge)
+	return sdkGaugeFunc(func(v float64) {
+		g.Set(int64(v), h.values...)
+	})
+}
+
+func (h *sdkMetricsHandler[Metrics, Counter, Gauge, Timer]) Timer(name string) client.MetricsTimer {
+	t := h.load("timer", name, func() any {
+		return h.metrics.NewTimer(name, h.keys...)
+	}).(Timer)
+	return sdkTimerFunc(func(d time.Duration) {
+		t.Timing(d.Nanoseconds(), h.values...)
+	})
+}
+
+// load returns the cached metric of the given kind, name and label keys,
+// creating it if necessary, as the SDK obtains metrics on every use.
+func (h *sdkMetricsHandler[Metrics, Counter, Gauge, Timer]) load(kind, name string, create func() any) any {
+	key := kind + ":" + name + ":" + strings.Join(h.keys, ",")
+	if m, ok := h.cache.Load(key); ok {
+		return m
+	}
+	m, _ := h.cache.LoadOrStore(key, create())
+	return m
+}
@@ -0,0 +1,292 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	QueryProcessorType = "temporal_query"
+)
+
+type (
+	QueryProcessor[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangMutateFrom(Mapping, Message) (Message, error)
+			BloblangQuery(Mapping) (Message, error)
+			Copy() Message
+			SetStructured(any)
+		},
+		MessageBatch any,
+	] struct {
+		args       Mapping
+		argsExists bool
+		clientConn
+		dc                   converter.DataConverter
+		queryRejectCondition enumspb.QueryRejectCondition
+		queryType            InterpolatedString
+		resultMapping        Mapping
+		resultMappingExists  bool
+		runID                InterpolatedString
+		runIDExists          bool
+		toBatch              func([]Message) MessageBatch
+		workflowID           InterpolatedString
+	}
+
+	QueryProcessorOptions[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangMutateFrom(Mapping, Message) (Message, error)
+			BloblangQuery(Mapping) (Message, error)
+			Copy() Message
+			SetStructured(any)
+		},
+		MessageBatch any,
+	] func(*QueryProcessor[InterpolatedString, Mapping, Message, MessageBatch]) error
+)
+
+func NewQueryProcessorConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Queries a Temporal workflow for each message and replaces the message with the query result.").
+		Fields(newClientConfigFields[Field](fields, false)...).
+		Fields(
+			fields.NewBloblangField("args").
+				Description("Query argument mapping, defaults to no arguments").
+				Optional(),
+			fields.NewStringEnumField("query_reject_condition", "none", "not_open", "not_completed_cleanly").
+				Description("Rejects the query if the workflow is not in the required state").
+				Default("none"),
+			fields.NewInterpolatedStringField("query_type").
+				Description("Query type name"),
+			fields.NewBloblangField("result_mapping").
+				Description("Maps the query result onto the original message, where root is the original message and this is the query result").
+				Optional(),
+			fields.NewInterpolatedStringField("run_id").
+				Description("Workflow run ID, defaults to the current run").
+				Optional(),
+			fields.NewInterpolatedStringField("workflow_id").
+				Description("Workflow ID"),
+		)
+}
+
+func NewQueryProcessor[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangMutateFrom(Mapping, Message) (Message, error)
+		BloblangQuery(Mapping) (Message, error)
+		Copy() Message
+		SetStructured(any)
+	},
+	MessageBatch any,
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, toBatch func([]Message) MessageBatch, opts ...QueryProcessorOptions[InterpolatedString, Mapping, Message, MessageBatch]) (p *QueryProcessor[InterpolatedString, Mapping, Message, MessageBatch], err error) {
+	p = &QueryProcessor[InterpolatedString, Mapping, Message, MessageBatch]{
+		toBatch: toBatch,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	setClientTelemetry(&p.clientOpts, mgr)
+	if p.clientOpts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, p.clientOpts, p.dc); err != nil {
+		return nil, err
+	}
+	if err := initClientConn(&p.clientConn, conf, mgr); err != nil {
+		return nil, err
+	}
+	if conf.Contains("args") {
+		p.argsExists = true
+		if p.args, err = conf.FieldBloblang("args"); err != nil {
+			return nil, err
+		}
+	}
+	queryRejectCondition, err := conf.FieldString("query_reject_condition")
+	if err != nil {
+		return nil, err
+	}
+	switch queryRejectCondition {
+	case "none":
+		p.queryRejectCondition = enumspb.QUERY_REJECT_CONDITION_NONE
+	case "not_open":
+		p.queryRejectCondition = enumspb.QUERY_REJECT_CONDITION_NOT_OPEN
+	case "not_completed_cleanly":
+		p.queryRejectCondition = enumspb.QUERY_REJECT_CONDITION_NOT_COMPLETED_CLEANLY
+	default:
+		return nil, fmt.Errorf("invalid query_reject_condition: %s", queryRejectCondition)
+	}
+	if p.queryType, err = conf.FieldInterpolatedString("query_type"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("result_mapping") {
+		p.resultMappingExists = true
+		if p.resultMapping, err = conf.FieldBloblang("result_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("run_id") {
+		p.runIDExists = true
+		if p.runID, err = conf.FieldInterpolatedString("run_id"); err != nil {
+			return nil, err
+		}
+	}
+	if p.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
+		return nil, err
+	}
+	if err := p.connectLazy(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *QueryProcessor[InterpolatedString, Mapping, Message, MessageBatch]) Close(ctx context.Context) error {
+	p.close()
+	return nil
+}
+
+func (p *QueryProcessor[InterpolatedString, Mapping, Message, MessageBatch]) Process(ctx context.Context, msg Message) (result MessageBatch, err error) {
+	if err := p.ensureConnected(ctx); err != nil {
+		return result, err
+	}
+	req := client.QueryWorkflowWithOptionsRequest{
+		QueryRejectCondition: p.queryRejectCondition,
+	}
+	if req.WorkflowID, err = p.workflowID.TryString(msg); err != nil {
+		return result, fmt.Errorf("error evaluating workflow_id: %w", err)
+	}
+	if p.runIDExists {
+		if req.RunID, err = p.runID.TryString(msg); err != nil {
+			return result, fmt.Errorf("error evaluating run_id: %w", err)
+		}
+	}
+	if req.QueryType, err = p.queryType.TryString(msg); err != nil {
+		return result, fmt.Errorf("error evaluating query_type: %w", err)
+	}
+	if p.argsExists {
+		args, err := msg.BloblangQuery(p.args)
+		if err != nil {
+			return result, fmt.Errorf("error evaluating args: %w", err)
+		}
+		if req.Args, err = newArgs[Mapping](args); err != nil {
+			return result, err
+		}
+	}
+
+	resp, err := p.client.QueryWorkflowWithOptions(ctx, &req)
+	if err != nil {
+		var notFound *serviceerror.NotFound
+		var queryFailed *serviceerror.QueryFailed
+		switch {
+		case errors.As(err, &notFound):
+			return result, fmt.Errorf("error querying workflow %s: workflow not found: %w", req.WorkflowID, err)
+		case errors.As(err, &queryFailed):
+			return result, fmt.Errorf("error querying workflow %s: query %s failed (is the query handler registered?): %w", req.WorkflowID, req.QueryType, err)
+		default:
+			return result, fmt.Errorf("error querying workflow %s: %w", req.WorkflowID, err)
+		}
+	}
+	if resp.QueryRejected != nil {
+		return result, fmt.Errorf("query %s rejected by workflow %s with status %s", req.QueryType, req.WorkflowID, resp.QueryRejected.GetStatus())
+	}
+
+	var v any
+	if resp.QueryResult != nil && resp.QueryResult.HasValue() {
+		if err := resp.QueryResult.Get(&v); err != nil {
+			return result, fmt.Errorf("error decoding query result: %w", err)
+		}
+	}
+	res := msg.Copy()
+	res.SetStructured(v)
+	if p.resultMappingExists {
+		if res, err = msg.Copy().BloblangMutateFrom(p.resultMapping, res); err != nil {
+			return result, fmt.Errorf("error applying result_mapping: %w", err)
+		}
+		var empty Message
+		if reflect.DeepEqual(res, empty) {
+			return p.toBatch(nil), nil
+		}
+	}
+	return p.toBatch([]Message{res}), nil
+}
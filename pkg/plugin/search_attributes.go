@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+)
+
+// searchAttributeTypes lists the supported search_attribute_types values.
+var searchAttributeTypes = []string{"bool", "datetime", "double", "int", "keyword", "keyword_list", "text"}
+
+// parseSearchAttributeTypes validates the declared search attribute types,
+// returning nil if no types are declared.
+func parseSearchAttributeTypes(types map[string]string) (map[string]string, error) {
+	if len(types) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string, len(types))
+	for name, typ := range types {
+		typ = strings.ToLower(typ)
+		idx := sort.SearchStrings(searchAttributeTypes, typ)
+		if idx == len(searchAttributeTypes) || searchAttributeTypes[idx] != typ {
+			return nil, fmt.Errorf("invalid search_attribute_types.%s: expected one of %s, got: %s", name, strings.Join(searchAttributeTypes, ", "), typ)
+		}
+		parsed[name] = typ
+	}
+	return parsed, nil
+}
+
+// newSearchAttributes converts the result of a search_attributes mapping into
+// typed search attributes using the declared types. Null values are omitted.
+func newSearchAttributes(types map[string]string, values map[string]any) (temporal.SearchAttributes, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	updates := make([]temporal.SearchAttributeUpdate, 0, len(values))
+	for _, name := range names {
+		v := values[name]
+		if v == nil {
+			continue
+		}
+		typ, ok := types[name]
+		if !ok {
+			return temporal.SearchAttributes{}, fmt.Errorf("search attribute %s: type not declared in search_attribute_types", name)
+		}
+		update, err := newSearchAttributeUpdate(name, typ, v)
+		if err != nil {
+			return temporal.SearchAttributes{}, fmt.Errorf("search attribute %s: %w", name, err)
+		}
+		updates = append(updates, update)
+	}
+	return temporal.NewSearchAttributes(updates...), nil
+}
+
+func newSearchAttributeUpdate(name, typ string, v any) (temporal.SearchAttributeUpdate, error) {
+	switch typ {
+	case "bool":
+		b, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyBool(name).ValueSet(b), nil
+	case "datetime":
+		t, err := toTime(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyTime(name).ValueSet(t), nil
+	case "double":
+		f, err := toFloat64(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyFloat64(name).ValueSet(f), nil
+	case "int":
+		i, err := toInt64(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyInt64(name).ValueSet(i), nil
+	case "keyword":
+		s, err := toString(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyKeyword(name).ValueSet(s), nil
+	case "keyword_list":
+		var list []string
+		switch x := v.(type) {
+		case []any:
+			list = make([]string, 0, len(x))
+			for i, item := range x {
+				s, err := toString(item)
+				if err != nil {
+					return nil, fmt.Errorf("index %d: %w", i, err)
+				}
+				list = append(list, s)
+			}
+		case []string:
+			list = x
+		default:
+			s, err := toString(v)
+			if err != nil {
+				return nil, err
+			}
+			list = []string{s}
+		}
+		return temporal.NewSearchAttributeKeyKeywordList(name).ValueSet(list), nil
+	case "text":
+		s, err := toString(v)
+		if err != nil {
+			return nil, err
+		}
+		return temporal.NewSearchAttributeKeyString(name).ValueSet(s), nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %s", typ)
+	}
+}
+
+func toBool(v any) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case string:
+		b, err := strconv.ParseBool(x)
+		if err != nil {
+			return false, fmt.Errorf("expected bool, got: %q", x)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("expected bool, got: %T", v)
+	}
+}
+
+func toFloat64(v any) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case uint64:
+		return float64(x), nil
+	case json.Number:
+		return x.Float64()
+	case string:
+		f, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected double, got: %q", x)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("expected double, got: %T", v)
+	}
+}
+
+func toInt64(v any) (int64, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case int64:
+		return x, nil
+	case uint64:
+		if x > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int", x)
+		}
+		return int64(x), nil
+	case float64:
+		if x != math.Trunc(x) || x > math.MaxInt64 || x < math.MinInt64 {
+			return 0, fmt.Errorf("expected int, got: %v", x)
+		}
+		return int64(x), nil
+	case json.Number:
+		return x.Int64()
+	case string:
+		i, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected int, got: %q", x)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("expected int, got: %T", v)
+	}
+}
+
+func toString(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return x, nil
+	case []byte:
+		return string(x), nil
+	case bool, float32, float64, int, int32, int64, uint64, json.Number:
+		return fmt.Sprint(x), nil
+	default:
+		return "", fmt.Errorf("expected string, got: %T", v)
+	}
+}
+
+// toTime accepts timestamps, RFC 3339 strings, and unix timestamps in seconds.
+func toTime(v any) (time.Time, error) {
+	switch x := v.(type) {
+	case time.Time:
+		return x, nil
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, x)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("expected RFC 3339 datetime, got: %q", x)
+		}
+		return t, nil
+	case int, int32, int64, uint64, float64, json.Number:
+		secs, err := toInt64(x)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(secs, 0).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("expected datetime, got: %T", v)
+	}
+}
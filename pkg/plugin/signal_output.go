@@ -0,0 +1,235 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	SignalOutputType = "temporal_signal"
+)
+
+type (
+	SignalOutput[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+			MetaWalk(func(string, string) error) error
+		},
+	] struct {
+		args       Mapping
+		argsExists bool
+		clientConn
+		dc                converter.DataConverter
+		propagateMetadata *metadataFilter
+		runID             InterpolatedString
+		runIDExists       bool
+		signalName        InterpolatedString
+		workflowID        InterpolatedString
+	}
+
+	SignalOutputOptions[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+			MetaWalk(func(string, string) error) error
+		},
+	] func(*SignalOutput[InterpolatedString, Mapping, Message]) error
+)
+
+func NewSignalOutputConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Signals a running Temporal workflow for each message as input.").
+		Fields(newClientConfigFields[Field](fields, false)...).
+		Fields(
+			fields.NewBloblangField("args").
+				Description("Signal argument mapping, defaults to the message contents").
+				Optional(),
+			fields.NewIntField("max_in_flight").
+				Description("Maximum number of pending signals").
+				Default(64),
+			newPropagateMetadataConfigField[Field](fields),
+			fields.NewInterpolatedStringField("run_id").
+				Description("Workflow run ID, defaults to the current run").
+				Optional(),
+			fields.NewInterpolatedStringField("signal_name").
+				Description("Signal name"),
+			fields.NewInterpolatedStringField("workflow_id").
+				Description("Workflow ID"),
+		)
+}
+
+func NewSignalOutput[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+		MetaWalk(func(string, string) error) error
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, opts ...SignalOutputOptions[InterpolatedString, Mapping, Message]) (o *SignalOutput[InterpolatedString, Mapping, Message], maxInFlight int, err error) {
+	o = &SignalOutput[InterpolatedString, Mapping, Message]{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, 0, err
+		}
+	}
+	setClientTelemetry(&o.clientOpts, mgr)
+	if o.clientOpts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, o.clientOpts, o.dc); err != nil {
+		return nil, 0, err
+	}
+	if err := initClientConn(&o.clientConn, conf, mgr); err != nil {
+		return nil, 0, err
+	}
+	if o.propagateMetadata, err = parseMetadataFilter(conf); err != nil {
+		return nil, 0, err
+	}
+	if o.propagateMetadata != nil {
+		o.clientOpts.ContextPropagators = append(o.clientOpts.ContextPropagators, NewMetadataPropagator())
+	}
+	if conf.Contains("args") {
+		o.argsExists = true
+		if o.args, err = conf.FieldBloblang("args"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+		return nil, 0, err
+	}
+	if conf.Contains("run_id") {
+		o.runIDExists = true
+		if o.runID, err = conf.FieldInterpolatedString("run_id"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if o.signalName, err = conf.FieldInterpolatedString("signal_name"); err != nil {
+		return nil, 0, err
+	}
+	if o.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
+		return nil, 0, err
+	}
+	return o, maxInFlight, nil
+}
+
+func (o *SignalOutput[InterpolatedString, Mapping, Message]) Close(ctx context.Context) error {
+	o.close()
+	return nil
+}
+
+func (o *SignalOutput[InterpolatedString, Mapping, Message]) Connect(ctx context.Context) error {
+	return o.connect(ctx)
+}
+
+func (o *SignalOutput[InterpolatedString, Mapping, Message]) Write(ctx context.Context, msg Message) (err error) {
+	if o.propagateMetadata != nil {
+		if ctx, err = withMetadata(ctx, o.propagateMetadata, msg); err != nil {
+			return err
+		}
+	}
+	workflowID, err := o.workflowID.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("error evaluating workflow_id: %w", err)
+	}
+	var runID string
+	if o.runIDExists {
+		if runID, err = o.runID.TryString(msg); err != nil {
+			return fmt.Errorf("error evaluating run_id: %w", err)
+		}
+	}
+	signalName, err := o.signalName.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("error evaluating signal_name: %w", err)
+	}
+	if o.argsExists {
+		if msg, err = msg.BloblangQuery(o.args); err != nil {
+			return fmt.Errorf("error evaluating args: %w", err)
+		}
+	}
+
+	args, err := newArgs[Mapping](msg)
+	if err != nil {
+		return err
+	}
+	var arg any
+	if len(args) > 0 {
+		arg = args[0]
+	}
+	if err := o.client.SignalWorkflow(ctx, workflowID, runID, signalName, arg); err != nil {
+		return fmt.Errorf("error signaling workflow: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/log"
+)
+
+// tlsReloader loads client certificates and CAs from files, reloading them
+// when the files change so that rotated certificates are used for new
+// connections without a restart. When a reload fails, the previously loaded
+// certificates continue to be used.
+type tlsReloader struct {
+	caFile     string
+	cert       *tls.Certificate
+	certFile   string
+	host       string
+	keyFile    string
+	logger     log.Logger
+	metrics    client.MetricsHandler
+	modTimes   map[string]time.Time
+	mu         sync.Mutex
+	rootCAs    *x509.CertPool
+	serverName string
+}
+
+func newTLSReloader(logger log.Logger, metrics client.MetricsHandler) *tlsReloader {
+	return &tlsReloader{
+		logger:  logger,
+		metrics: metrics,
+	}
+}
+
+// apply configures cfg to use the certificates loaded by the reloader, where
+// server certificates are verified against the current CAs by
+// VerifyConnection instead of the default verification. The server name is
+// verified against cfg.ServerName, falling back to the host of hostPort.
+func (r *tlsReloader) apply(cfg *tls.Config, hostPort string) {
+	if r.certFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.reloadIfChanged()
+			return r.cert, nil
+		}
+	}
+	if r.caFile != "" && !cfg.InsecureSkipVerify {
+		r.serverName = cfg.ServerName
+		if host, _, err := net.SplitHostPort(hostPort); err == nil && !isInterpolated(nil, host) {
+			r.host = host
+		}
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = r.verifyConnection
+	}
+}
+
+// load loads the certificates from their files.
+func (r *tlsReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loadLocked()
+}
+
+func (r *tlsReloader) loadLocked() error {
+	modTimes := make(map[string]time.Time, 3)
+	for _, path := range []string{r.caFile, r.certFile, r.keyFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		modTimes[path] = info.ModTime()
+	}
+
+	var rootCAs *x509.CertPool
+	if r.caFile != "" {
+		caBytes, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		if rootCAs, err = newCertPool(caBytes); err != nil {
+			return err
+		}
+	}
+	var cert *tls.Certificate
+	if r.certFile != "" {
+		clientCert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("error loading client certificate: %w", err)
+		}
+		cert = &clientCert
+	}
+	r.cert, r.modTimes, r.rootCAs = cert, modTimes, rootCAs
+	return nil
+}
+
+// reloadIfChanged reloads the certificates if any of their files changed,
+// logging and recording a metric if the reload fails.
+func (r *tlsReloader) reloadIfChanged() {
+	changed := false
+	for path, modTime := range r.modTimes {
+		if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(modTime) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := r.loadLocked(); err != nil {
+		if r.logger != nil {
+			r.logger.Warn("Error reloading TLS files, using previously loaded certificates", "error", err)
+		}
+		if r.metrics != nil {
+			r.metrics.Counter("temporal_tls_reload_error").Inc(1)
+		}
+	}
+}
+
+// verifyConnection verifies the server certificate chain against the current
+// CAs, and that it is valid for the configured server name, the name sent
+// using SNI, or the dialed host, in that order, as SNI is not sent for IP
+// addresses.
+func (r *tlsReloader) verifyConnection(cs tls.ConnectionState) error {
+	r.mu.Lock()
+	r.reloadIfChanged()
+	rootCAs := r.rootCAs
+	r.mu.Unlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server did not provide a certificate")
+	}
+	name := r.serverName
+	if name == "" {
+		name = cs.ServerName
+	}
+	if name == "" {
+		name = r.host
+	}
+	if name == "" {
+		return errors.New("cannot verify the server certificate without a server name, specify tls.server_name")
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+		Roots:         rootCAs,
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+func newCertPool(caBytes []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("invalid CA cert data")
+	}
+	return pool, nil
+}
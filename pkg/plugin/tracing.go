@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/contrib/opentelemetry"
+	"go.temporal.io/sdk/interceptor"
+)
+
+const tracerName = "github.com/cludden/benthos-plugin-temporal"
+
+// tracePropagator propagates W3C trace context and baggage, both from message
+// metadata and into Temporal headers.
+var tracePropagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+// newTracingInterceptor returns a Temporal client interceptor that creates
+// spans using tp and propagates them to workers via Temporal headers.
+func newTracingInterceptor(tp trace.TracerProvider) (interceptor.ClientInterceptor, error) {
+	return opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{
+		Tracer:            tp.Tracer(tracerName),
+		TextMapPropagator: tracePropagator,
+	})
+}
+
+// withTraceContext returns ctx with the trace context of msg, using the span
+// attached to the message if one exists, otherwise extracting W3C trace
+// context from the message metadata.
+func withTraceContext[
+	Message interface {
+		Context() context.Context
+		MetaGet(string) (string, bool)
+	},
+](ctx context.Context, msg Message) context.Context {
+	if span := trace.SpanFromContext(msg.Context()); span.SpanContext().IsValid() {
+		return trace.ContextWithSpan(ctx, span)
+	}
+	return tracePropagator.Extract(ctx, metadataCarrier[Message]{msg: msg})
+}
+
+// metadataCarrier is a read-only propagation.TextMapCarrier backed by message
+// metadata.
+type metadataCarrier[
+	Message interface {
+		MetaGet(string) (string, bool)
+	},
+] struct {
+	msg Message
+}
+
+func (c metadataCarrier[Message]) Get(key string) string {
+	v, _ := c.msg.MetaGet(key)
+	return v
+}
+
+func (c metadataCarrier[Message]) Keys() []string {
+	return nil
+}
+
+func (c metadataCarrier[Message]) Set(string, string) {}
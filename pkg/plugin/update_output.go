@@ -0,0 +1,336 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	UpdateOutputType = "temporal_update"
+)
+
+type (
+	UpdateOutput[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+			MetaWalk(func(string, string) error) error
+		},
+	] struct {
+		args       Mapping
+		argsExists bool
+		clientConn
+		dc                 converter.DataConverter
+		propagateMetadata  *metadataFilter
+		runID              InterpolatedString
+		runIDExists        bool
+		startExists        bool
+		startMapping       Mapping
+		startMappingExists bool
+		startTaskQueue     InterpolatedString
+		startWorkflowType  InterpolatedString
+		updateID           InterpolatedString
+		updateIDExists     bool
+		updateName         InterpolatedString
+		waitForStage       client.WorkflowUpdateStage
+		workflowID         InterpolatedString
+	}
+
+	UpdateOutputOptions[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+			MetaWalk(func(string, string) error) error
+		},
+	] func(*UpdateOutput[InterpolatedString, Mapping, Message]) error
+)
+
+func NewUpdateOutputConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Sends a Temporal workflow update for each message as input.").
+		Fields(newClientConfigFields[Field](fields, false)...).
+		Fields(
+			fields.NewBloblangField("args").
+				Description("Update argument mapping, defaults to the message contents").
+				Optional(),
+			fields.NewIntField("max_in_flight").
+				Description("Maximum number of pending updates").
+				Default(64),
+			newPropagateMetadataConfigField[Field](fields),
+			fields.NewInterpolatedStringField("run_id").
+				Description("Workflow run ID, defaults to the current run").
+				Optional(),
+			fields.NewObjectField("start",
+				fields.NewBloblangField("mapping").
+					Description("Input mapping, defaults to the message contents").
+					Optional(),
+				fields.NewInterpolatedStringField("task_queue").
+					Description("Worker task queue name"),
+				fields.NewInterpolatedStringField("workflow_type").
+					Description("Workflow type name"),
+			).
+				Description("Starts the workflow if it is not already running (update-with-start)").
+				Optional(),
+			fields.NewInterpolatedStringField("update_id").
+				Description("Update ID, used to deduplicate redelivered updates").
+				Optional(),
+			fields.NewInterpolatedStringField("update_name").
+				Description("Update name"),
+			fields.NewStringEnumField("wait_for_stage", "accepted", "completed").
+				Description("Update stage to wait for before acknowledging a message").
+				Default("completed"),
+			fields.NewInterpolatedStringField("workflow_id").
+				Description("Workflow ID"),
+		)
+}
+
+func NewUpdateOutput[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+		MetaWalk(func(string, string) error) error
+	},
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, opts ...UpdateOutputOptions[InterpolatedString, Mapping, Message]) (o *UpdateOutput[InterpolatedString, Mapping, Message], maxInFlight int, err error) {
+	o = &UpdateOutput[InterpolatedString, Mapping, Message]{}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, 0, err
+		}
+	}
+	setClientTelemetry(&o.clientOpts, mgr)
+	if o.clientOpts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, o.clientOpts, o.dc); err != nil {
+		return nil, 0, err
+	}
+	if err := initClientConn(&o.clientConn, conf, mgr); err != nil {
+		return nil, 0, err
+	}
+	if o.propagateMetadata, err = parseMetadataFilter(conf); err != nil {
+		return nil, 0, err
+	}
+	if o.propagateMetadata != nil {
+		o.clientOpts.ContextPropagators = append(o.clientOpts.ContextPropagators, NewMetadataPropagator())
+	}
+	if conf.Contains("args") {
+		o.argsExists = true
+		if o.args, err = conf.FieldBloblang("args"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+		return nil, 0, err
+	}
+	if conf.Contains("run_id") {
+		o.runIDExists = true
+		if o.runID, err = conf.FieldInterpolatedString("run_id"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if conf.Contains("start") {
+		if o.runIDExists {
+			return nil, 0, errors.New("cannot specify both run_id and start")
+		}
+		o.startExists = true
+		if conf.Contains("start", "mapping") {
+			o.startMappingExists = true
+			if o.startMapping, err = conf.FieldBloblang("start", "mapping"); err != nil {
+				return nil, 0, err
+			}
+		}
+		if o.startTaskQueue, err = conf.FieldInterpolatedString("start", "task_queue"); err != nil {
+			return nil, 0, err
+		}
+		if o.startWorkflowType, err = conf.FieldInterpolatedString("start", "workflow_type"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if conf.Contains("update_id") {
+		o.updateIDExists = true
+		if o.updateID, err = conf.FieldInterpolatedString("update_id"); err != nil {
+			return nil, 0, err
+		}
+	}
+	if o.updateName, err = conf.FieldInterpolatedString("update_name"); err != nil {
+		return nil, 0, err
+	}
+	waitForStage, err := conf.FieldString("wait_for_stage")
+	if err != nil {
+		return nil, 0, err
+	}
+	switch waitForStage {
+	case "accepted":
+		o.waitForStage = client.WorkflowUpdateStageAccepted
+	case "completed":
+		o.waitForStage = client.WorkflowUpdateStageCompleted
+	default:
+		return nil, 0, fmt.Errorf("invalid wait_for_stage: %s", waitForStage)
+	}
+	if o.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
+		return nil, 0, err
+	}
+	return o, maxInFlight, nil
+}
+
+func (o *UpdateOutput[InterpolatedString, Mapping, Message]) Close(ctx context.Context) error {
+	o.close()
+	return nil
+}
+
+func (o *UpdateOutput[InterpolatedString, Mapping, Message]) Connect(ctx context.Context) error {
+	return o.connect(ctx)
+}
+
+func (o *UpdateOutput[InterpolatedString, Mapping, Message]) Write(ctx context.Context, msg Message) (err error) {
+	if o.propagateMetadata != nil {
+		if ctx, err = withMetadata(ctx, o.propagateMetadata, msg); err != nil {
+			return err
+		}
+	}
+	opts := client.UpdateWorkflowOptions{
+		WaitForStage: o.waitForStage,
+	}
+	if opts.WorkflowID, err = o.workflowID.TryString(msg); err != nil {
+		return fmt.Errorf("error evaluating workflow_id: %w", err)
+	}
+	if o.runIDExists {
+		if opts.RunID, err = o.runID.TryString(msg); err != nil {
+			return fmt.Errorf("error evaluating run_id: %w", err)
+		}
+	}
+	if o.updateIDExists {
+		if opts.UpdateID, err = o.updateID.TryString(msg); err != nil {
+			return fmt.Errorf("error evaluating update_id: %w", err)
+		}
+	}
+	if opts.UpdateName, err = o.updateName.TryString(msg); err != nil {
+		return fmt.Errorf("error evaluating update_name: %w", err)
+	}
+	update := msg
+	if o.argsExists {
+		if update, err = msg.BloblangQuery(o.args); err != nil {
+			return fmt.Errorf("error evaluating args: %w", err)
+		}
+	}
+	if opts.Args, err = newArgs[Mapping](update); err != nil {
+		return err
+	}
+
+	var handle client.WorkflowUpdateHandle
+	if o.startExists {
+		startOpts := client.StartWorkflowOptions{
+			ID:                       opts.WorkflowID,
+			WorkflowIDConflictPolicy: enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING,
+		}
+		if startOpts.TaskQueue, err = o.startTaskQueue.TryString(msg); err != nil {
+			return fmt.Errorf("error evaluating start.task_queue: %w", err)
+		}
+		workflowType, err := o.startWorkflowType.TryString(msg)
+		if err != nil {
+			return fmt.Errorf("error evaluating start.workflow_type: %w", err)
+		}
+		if o.startMappingExists {
+			if msg, err = msg.BloblangQuery(o.startMapping); err != nil {
+				return fmt.Errorf("error applying start mapping: %w", err)
+			}
+		}
+		args, err := newArgs[Mapping](msg)
+		if err != nil {
+			return err
+		}
+		handle, err = o.client.UpdateWithStartWorkflow(ctx, client.UpdateWithStartWorkflowOptions{
+			StartWorkflowOperation: o.client.NewWithStartWorkflowOperation(startOpts, workflowType, args...),
+			UpdateOptions:          opts,
+		})
+		if err != nil {
+			return fmt.Errorf("error updating workflow with start: %w", err)
+		}
+	} else if handle, err = o.client.UpdateWorkflow(ctx, opts); err != nil {
+		return fmt.Errorf("error updating workflow: %w", err)
+	}
+	if o.waitForStage != client.WorkflowUpdateStageCompleted {
+		return nil
+	}
+	if err := handle.Get(ctx, nil); err != nil {
+		return fmt.Errorf("workflow update failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,728 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cludden/protoc-gen-go-temporal/pkg/scheme"
+	"go.opentelemetry.io/otel/trace"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+type (
+	// workflowExecutor starts workflow executions from messages, and is shared
+	// by the temporal_workflow output and processor.
+	workflowExecutor[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangQuery(Mapping) (Message, error)
+			Context() context.Context
+			MetaGet(string) (string, bool)
+			MetaWalk(func(string, string) error) error
+		},
+	] struct {
+		address          InterpolatedString
+		addressExists    bool
+		args             Mapping
+		argsExists       bool
+		argsMessageTypes []string
+		clientConn
+		cronSchedule           string
+		dc                     converter.DataConverter
+		errorWhenStarted       bool
+		executionTimeout       InterpolatedString
+		executionTimeoutExists bool
+		headers                Mapping
+		headersExists          bool
+		mapping                Mapping
+		mappingExists          bool
+		memo                   Mapping
+		memoExists             bool
+		namespace              InterpolatedString
+		namespaceExists        bool
+		propagateMetadata      *metadataFilter
+		inputMessageType       InterpolatedString
+		inputMessageTypeExists bool
+		retryPolicy            *temporal.RetryPolicy
+		runTimeout             InterpolatedString
+		runTimeoutExists       bool
+		scheme                 *scheme.Scheme
+		searchAttributes       Mapping
+		searchAttributesExists bool
+		searchAttributeTypes   map[string]string
+		signalArgs             Mapping
+		signalArgsExists       bool
+		signalExists           bool
+		signalName             InterpolatedString
+		startDelay             InterpolatedString
+		startDelayExists       bool
+		taskQueue              InterpolatedString
+		taskTimeout            InterpolatedString
+		taskTimeoutExists      bool
+		tracing                bool
+		workflowID             InterpolatedString
+		workflowIDConflict     enumspb.WorkflowIdConflictPolicy
+		workflowIDReuse        enumspb.WorkflowIdReusePolicy
+		workflowType           InterpolatedString
+	}
+)
+
+// newWorkflowExecutorConfigFields returns the fields used to configure a
+// workflowExecutor, including the shared connection fields.
+func newWorkflowExecutorConfigFields[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewDurationField(string) Field
+		NewFloatField(string) Field
+		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](fields FieldProvider) []Field {
+	return append(newClientConfigFields[Field](fields, true),
+		fields.NewBloblangField("args").
+			Description("Workflow arguments mapping, evaluated against the original message, where each element of the resulting array is passed as a positional argument").
+			Optional(),
+		fields.NewStringListField("args_proto_message_names").
+			Description("Full names of the proto messages for each positional argument, where an empty string leaves the argument as is").
+			Optional(),
+		fields.NewObjectField("client_pool",
+			fields.NewDurationField("idle_timeout").
+				Description("Duration after which a client that has not been used is closed, where 0s disables idle eviction").
+				Default("5m"),
+			fields.NewIntField("max_clients").
+				Description("Maximum number of clients, where the least recently used idle client is closed when the limit is reached").
+				Default(100),
+		).
+			Description("Pool of clients used when address or namespace are interpolated, which lazily dials a client for each address and namespace"),
+		fields.NewStringField("cron_schedule").
+			Description("Cron schedule for the workflow").
+			Optional(),
+		fields.NewInterpolatedStringField("execution_timeout").
+			Description("Timeout for the entire workflow execution, including retries and continue-as-new, e.g. 24h").
+			Optional(),
+		fields.NewBloblangField("headers").
+			Description("Workflow headers mapping, evaluated against the original message").
+			Optional(),
+		fields.NewInterpolatedStringField("input_proto_message_name").
+			Description("Full name of input proto message").
+			Optional(),
+		fields.NewBloblangField("mapping").
+			Description("Input mapping").
+			Optional(),
+		fields.NewBloblangField("memo").
+			Description("Workflow memo mapping, evaluated against the original message").
+			Optional(),
+		newPropagateMetadataConfigField[Field](fields),
+		fields.NewObjectField("retry_policy",
+			fields.NewFloatField("backoff_coefficient").
+				Description("Coefficient used to calculate the next retry interval").
+				Optional(),
+			fields.NewDurationField("initial_interval").
+				Description("Backoff interval for the first retry").
+				Optional(),
+			fields.NewIntField("maximum_attempts").
+				Description("Maximum number of attempts, where 0 means unlimited").
+				Optional(),
+			fields.NewDurationField("maximum_interval").
+				Description("Maximum backoff interval between retries").
+				Optional(),
+			fields.NewStringListField("non_retryable_error_types").
+				Description("Application error types that are not retried").
+				Optional(),
+		).
+			Description("Workflow retry policy, workflows are not retried by default").
+			Optional(),
+		fields.NewInterpolatedStringField("run_timeout").
+			Description("Timeout for a single workflow run, e.g. 1h").
+			Optional(),
+		fields.NewStringMapField("search_attribute_types").
+			Description("Declares the type of each search attribute, one of "+strings.Join(searchAttributeTypes, ", ")+", enabling typed search attributes").
+			Optional(),
+		fields.NewBloblangField("search_attributes").
+			Description("Search attributes mapping, evaluated against the original message").
+			Optional(),
+		fields.NewObjectField("signal",
+			fields.NewBloblangField("args").
+				Description("Signal argument mapping, defaults to the message contents").
+				Optional(),
+			fields.NewInterpolatedStringField("name").
+				Description("Signal name"),
+		).
+			Description("Signals the workflow, starting it first if it is not already running").
+			Optional(),
+		fields.NewInterpolatedStringField("start_delay").
+			Description("Delays the start of the workflow execution, e.g. 5m").
+			Optional(),
+		fields.NewInterpolatedStringField("task_queue").
+			Description("Worker task queue name"),
+		fields.NewInterpolatedStringField("task_timeout").
+			Description("Timeout for a single workflow task, e.g. 10s").
+			Optional(),
+		fields.NewBoolField("tracing").
+			Description("Propagates the trace context of each message, from its tracing span or W3C trace context metadata, to the workflow using the Temporal OpenTelemetry interceptor").
+			Default(false),
+		fields.NewInterpolatedStringField("workflow_id").
+			Description("Workflow ID"),
+		fields.NewStringEnumField("workflow_id_conflict_policy", "fail", "use_existing", "terminate_existing").
+			Description("Behavior when a workflow with the same ID is already running").
+			Optional(),
+		fields.NewStringEnumField("workflow_id_reuse_policy", "allow_duplicate", "allow_duplicate_failed_only", "reject_duplicate", "terminate_if_running").
+			Description("Behavior when a closed workflow with the same ID exists").
+			Optional(),
+		fields.NewInterpolatedStringField("workflow_type").
+			Description("Workflow type name"),
+	)
+}
+
+// parse parses the fields returned by newWorkflowExecutorConfigFields.
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) parse(conf interface {
+	Contains(...string) bool
+	FieldBloblang(...string) (Mapping, error)
+	FieldBool(...string) (bool, error)
+	FieldDuration(...string) (time.Duration, error)
+	FieldFloat(...string) (float64, error)
+	FieldInt(...string) (int, error)
+	FieldInterpolatedString(...string) (InterpolatedString, error)
+	FieldString(...string) (string, error)
+	FieldStringList(...string) ([]string, error)
+	FieldStringMap(...string) (map[string]string, error)
+}) (err error) {
+	if e.clientOpts, err = newClientOptions[InterpolatedString, Mapping, Message](conf, e.clientOpts, e.dc); err != nil {
+		return err
+	}
+	var interpolated bool
+	if conf.Contains("address") {
+		e.addressExists = true
+		if e.address, err = conf.FieldInterpolatedString("address"); err != nil {
+			return err
+		}
+		interpolated = isInterpolated(e.address, e.clientOpts.HostPort)
+	}
+	if conf.Contains("namespace") {
+		e.namespaceExists = true
+		if e.namespace, err = conf.FieldInterpolatedString("namespace"); err != nil {
+			return err
+		}
+		interpolated = interpolated || isInterpolated(e.namespace, e.clientOpts.Namespace)
+	}
+	if interpolated {
+		maxClients, err := conf.FieldInt("client_pool", "max_clients")
+		if err != nil {
+			return err
+		}
+		if maxClients < 1 {
+			return errors.New("client_pool.max_clients must be at least 1")
+		}
+		idleTimeout, err := conf.FieldDuration("client_pool", "idle_timeout")
+		if err != nil {
+			return err
+		}
+		e.pool = newClientPool(maxClients, idleTimeout)
+	}
+	if conf.Contains("args") {
+		if conf.Contains("mapping") || conf.Contains("input_proto_message_name") {
+			return errors.New("cannot specify args with mapping or input_proto_message_name")
+		}
+		e.argsExists = true
+		if e.args, err = conf.FieldBloblang("args"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("args_proto_message_names") {
+		if e.argsMessageTypes, err = conf.FieldStringList("args_proto_message_names"); err != nil {
+			return err
+		}
+		if len(e.argsMessageTypes) > 0 && !e.argsExists {
+			return errors.New("args_proto_message_names requires args")
+		}
+		if len(e.argsMessageTypes) > 0 && e.scheme == nil {
+			return errors.New("args_proto_message_names requires a protobuf scheme")
+		}
+	}
+	if conf.Contains("cron_schedule") {
+		if e.cronSchedule, err = conf.FieldString("cron_schedule"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("execution_timeout") {
+		e.executionTimeoutExists = true
+		if e.executionTimeout, err = conf.FieldInterpolatedString("execution_timeout"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("headers") {
+		e.headersExists = true
+		if e.headers, err = conf.FieldBloblang("headers"); err != nil {
+			return err
+		}
+		e.clientOpts.Interceptors = append(e.clientOpts.Interceptors, &headersInterceptor{})
+	}
+	if conf.Contains("input_proto_message_name") {
+		e.inputMessageTypeExists = true
+		if e.inputMessageType, err = conf.FieldInterpolatedString("input_proto_message_name"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("mapping") {
+		e.mappingExists = true
+		if e.mapping, err = conf.FieldBloblang("mapping"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("memo") {
+		e.memoExists = true
+		if e.memo, err = conf.FieldBloblang("memo"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("retry_policy") {
+		if e.retryPolicy, err = parseRetryPolicy(conf); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("run_timeout") {
+		e.runTimeoutExists = true
+		if e.runTimeout, err = conf.FieldInterpolatedString("run_timeout"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("search_attribute_types") {
+		types, err := conf.FieldStringMap("search_attribute_types")
+		if err != nil {
+			return err
+		}
+		if e.searchAttributeTypes, err = parseSearchAttributeTypes(types); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("search_attributes") {
+		e.searchAttributesExists = true
+		if e.searchAttributes, err = conf.FieldBloblang("search_attributes"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("signal") {
+		e.signalExists = true
+		if conf.Contains("signal", "args") {
+			e.signalArgsExists = true
+			if e.signalArgs, err = conf.FieldBloblang("signal", "args"); err != nil {
+				return err
+			}
+		}
+		if e.signalName, err = conf.FieldInterpolatedString("signal", "name"); err != nil {
+			return err
+		}
+	}
+	if conf.Contains("start_delay") {
+		if e.cronSchedule != "" {
+			return errors.New("cannot specify both cron_schedule and start_delay")
+		}
+		e.startDelayExists = true
+		if e.startDelay, err = conf.FieldInterpolatedString("start_delay"); err != nil {
+			return err
+		}
+	}
+	if e.taskQueue, err = conf.FieldInterpolatedString("task_queue"); err != nil {
+		return err
+	}
+	if conf.Contains("task_timeout") {
+		e.taskTimeoutExists = true
+		if e.taskTimeout, err = conf.FieldInterpolatedString("task_timeout"); err != nil {
+			return err
+		}
+	}
+	if e.propagateMetadata, err = parseMetadataFilter(conf); err != nil {
+		return err
+	}
+	if e.propagateMetadata != nil {
+		e.clientOpts.ContextPropagators = append(e.clientOpts.ContextPropagators, NewMetadataPropagator())
+	}
+	if e.tracing, err = conf.FieldBool("tracing"); err != nil {
+		return err
+	}
+	if e.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
+		return err
+	}
+	if conf.Contains("workflow_id_conflict_policy") {
+		policy, err := conf.FieldString("workflow_id_conflict_policy")
+		if err != nil {
+			return err
+		}
+		switch policy {
+		case "fail":
+			e.workflowIDConflict = enumspb.WORKFLOW_ID_CONFLICT_POLICY_FAIL
+		case "use_existing":
+			e.workflowIDConflict = enumspb.WORKFLOW_ID_CONFLICT_POLICY_USE_EXISTING
+		case "terminate_existing":
+			e.workflowIDConflict = enumspb.WORKFLOW_ID_CONFLICT_POLICY_TERMINATE_EXISTING
+		default:
+			return fmt.Errorf("invalid workflow_id_conflict_policy: %s", policy)
+		}
+	}
+	if conf.Contains("workflow_id_reuse_policy") {
+		policy, err := conf.FieldString("workflow_id_reuse_policy")
+		if err != nil {
+			return err
+		}
+		switch policy {
+		case "allow_duplicate":
+			e.workflowIDReuse = enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE
+		case "allow_duplicate_failed_only":
+			e.workflowIDReuse = enumspb.WORKFLOW_ID_REUSE_POLICY_ALLOW_DUPLICATE_FAILED_ONLY
+		case "reject_duplicate":
+			e.workflowIDReuse = enumspb.WORKFLOW_ID_REUSE_POLICY_REJECT_DUPLICATE
+		case "terminate_if_running":
+			if e.workflowIDConflict != enumspb.WORKFLOW_ID_CONFLICT_POLICY_UNSPECIFIED {
+				return errors.New("cannot specify both workflow_id_conflict_policy and a workflow_id_reuse_policy of terminate_if_running")
+			}
+			e.workflowIDReuse = enumspb.WORKFLOW_ID_REUSE_POLICY_TERMINATE_IF_RUNNING
+		default:
+			return fmt.Errorf("invalid workflow_id_reuse_policy: %s", policy)
+		}
+	}
+	if e.workflowType, err = conf.FieldInterpolatedString("workflow_type"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setTracerProvider installs the Temporal OpenTelemetry interceptor using tp
+// when tracing is enabled.
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) setTracerProvider(tp trace.TracerProvider) error {
+	if !e.tracing {
+		return nil
+	}
+	i, err := newTracingInterceptor(tp)
+	if err != nil {
+		return fmt.Errorf("error initializing tracing interceptor: %w", err)
+	}
+	e.clientOpts.Interceptors = append(e.clientOpts.Interceptors, i)
+	return nil
+}
+
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) Close(ctx context.Context) error {
+	e.close()
+	return nil
+}
+
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) Connect(ctx context.Context) error {
+	return e.connect(ctx)
+}
+
+// acquireClient returns the client used to execute the workflow for msg,
+// which is selected from the client pool when the address or namespace is
+// interpolated, along with a function that must be called with the result
+// once the client is no longer in use.
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) acquireClient(ctx context.Context, msg Message) (client.Client, func(error), error) {
+	if e.pool == nil {
+		return e.client, func(error) {}, nil
+	}
+	key := clientPoolKey{address: e.clientOpts.HostPort, namespace: e.clientOpts.Namespace}
+	var err error
+	if e.addressExists {
+		if key.address, err = e.address.TryString(msg); err != nil {
+			return nil, nil, fmt.Errorf("error evaluating address: %w", err)
+		}
+	}
+	if e.namespaceExists {
+		if key.namespace, err = e.namespace.TryString(msg); err != nil {
+			return nil, nil, fmt.Errorf("error evaluating namespace: %w", err)
+		}
+	}
+	c, err := e.pool.acquire(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.client, func(err error) { e.pool.release(c, err) }, nil
+}
+
+// execute starts a workflow execution using c, or signals an existing one
+// when signal-with-start is configured, for the given message. When
+// errorWhenStarted is set and the workflow is already running, the
+// existing run is returned alongside the error.
+func (e *workflowExecutor[InterpolatedString, Mapping, Message]) execute(ctx context.Context, c client.Client, msg Message) (run client.WorkflowRun, err error) {
+	if e.tracing {
+		ctx = withTraceContext(ctx, msg)
+	}
+	if e.propagateMetadata != nil {
+		if ctx, err = withMetadata(ctx, e.propagateMetadata, msg); err != nil {
+			return nil, err
+		}
+	}
+	opts := client.StartWorkflowOptions{
+		CronSchedule:                             e.cronSchedule,
+		RetryPolicy:                              e.retryPolicy,
+		WorkflowExecutionErrorWhenAlreadyStarted: e.errorWhenStarted,
+		WorkflowIDConflictPolicy:                 e.workflowIDConflict,
+		WorkflowIDReusePolicy:                    e.workflowIDReuse,
+	}
+	if opts.ID, err = e.workflowID.TryString(msg); err != nil {
+		return nil, fmt.Errorf("error evaluating workflow_id: %w", err)
+	}
+	if opts.TaskQueue, err = e.taskQueue.TryString(msg); err != nil {
+		return nil, fmt.Errorf("error evaluating task_queue: %w", err)
+	}
+	if e.executionTimeoutExists {
+		if opts.WorkflowExecutionTimeout, err = parseDuration(msg, e.executionTimeout, "execution_timeout"); err != nil {
+			return nil, err
+		}
+	}
+	if e.runTimeoutExists {
+		if opts.WorkflowRunTimeout, err = parseDuration(msg, e.runTimeout, "run_timeout"); err != nil {
+			return nil, err
+		}
+	}
+	if e.startDelayExists {
+		if opts.StartDelay, err = parseDuration(msg, e.startDelay, "start_delay"); err != nil {
+			return nil, err
+		}
+	}
+	if e.taskTimeoutExists {
+		if opts.WorkflowTaskTimeout, err = parseDuration(msg, e.taskTimeout, "task_timeout"); err != nil {
+			return nil, err
+		}
+	}
+	workflowType, err := e.workflowType.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating workflow_type: %w", err)
+	}
+	var signalName string
+	var signalArg any
+	if e.signalExists {
+		if signalName, err = e.signalName.TryString(msg); err != nil {
+			return nil, fmt.Errorf("error evaluating signal.name: %w", err)
+		}
+		signal := msg
+		if e.signalArgsExists {
+			if signal, err = msg.BloblangQuery(e.signalArgs); err != nil {
+				return nil, fmt.Errorf("error evaluating signal.args: %w", err)
+			}
+		}
+		signalArgs, err := newArgs[Mapping](signal)
+		if err != nil {
+			return nil, err
+		}
+		if len(signalArgs) > 0 {
+			signalArg = signalArgs[0]
+		}
+	}
+	if e.headersExists {
+		headers, err := queryObject[Mapping](msg, e.headers, "headers")
+		if err != nil {
+			return nil, err
+		}
+		encoded := make(map[string]*commonpb.Payload, len(headers))
+		for k, v := range headers {
+			if encoded[k], err = e.clientOpts.DataConverter.ToPayload(v); err != nil {
+				return nil, fmt.Errorf("error encoding header %s: %w", k, err)
+			}
+		}
+		ctx = withHeaders(ctx, encoded)
+	}
+	if e.memoExists {
+		if opts.Memo, err = queryObject[Mapping](msg, e.memo, "memo"); err != nil {
+			return nil, err
+		}
+	}
+	if e.searchAttributesExists {
+		sa, err := queryObject[Mapping](msg, e.searchAttributes, "search_attributes")
+		if err != nil {
+			return nil, err
+		}
+		if e.searchAttributeTypes != nil {
+			if opts.TypedSearchAttributes, err = newSearchAttributes(e.searchAttributeTypes, sa); err != nil {
+				return nil, fmt.Errorf("error evaluating search_attributes: %w", err)
+			}
+		} else {
+			opts.SearchAttributes = sa
+		}
+	}
+	if e.mappingExists {
+		if msg, err = msg.BloblangQuery(e.mapping); err != nil {
+			return nil, fmt.Errorf("error applying output mapping: %w", err)
+		}
+	}
+
+	var args []any
+	var empty Message
+	if e.argsExists {
+		positional, err := msg.BloblangQuery(e.args)
+		if err != nil {
+			return nil, fmt.Errorf("error evaluating args: %w", err)
+		}
+		if args, err = newPositionalArgs[Mapping](positional, e.scheme, e.argsMessageTypes); err != nil {
+			return nil, err
+		}
+	} else if !reflect.DeepEqual(msg, empty) {
+		var arg any
+		if e.scheme != nil && e.inputMessageTypeExists {
+			messageType, err := e.inputMessageType.TryString(msg)
+			if err != nil {
+				return nil, fmt.Errorf("error evaluating input_proto_message_name: %w", err)
+			}
+			pb, err := e.scheme.New(messageType)
+			if err != nil {
+				return nil, fmt.Errorf("error initializing new %s value: %w", messageType, err)
+			}
+			b, err := msg.AsBytes()
+			if err != nil {
+				return nil, fmt.Errorf("error serializing message bytes: %w", err)
+			}
+			if err = protojson.Unmarshal(b, pb); err != nil {
+				return nil, fmt.Errorf("error unmarshalling message proto: %w", err)
+			}
+			arg = pb
+		} else if arg, err = msg.AsStructured(); err != nil {
+			return nil, fmt.Errorf("error evaluating message as structured: %w", err)
+		}
+		args = append(args, arg)
+	}
+
+	if e.signalExists {
+		run, err = c.SignalWithStartWorkflow(ctx, opts.ID, signalName, signalArg, opts, workflowType, args...)
+	} else {
+		run, err = c.ExecuteWorkflow(ctx, opts, workflowType, args...)
+	}
+	if err != nil {
+		var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+		if errors.As(err, &alreadyStarted) {
+			return c.GetWorkflow(ctx, opts.ID, alreadyStarted.RunId), fmt.Errorf("error executing workflow: %w", err)
+		}
+		return nil, fmt.Errorf("error executing workflow: %w", err)
+	}
+	return run, nil
+}
+
+// parseRetryPolicy parses the retry_policy field, returning nil if no retry
+// policy fields are set.
+func parseRetryPolicy(conf interface {
+	Contains(...string) bool
+	FieldDuration(...string) (time.Duration, error)
+	FieldFloat(...string) (float64, error)
+	FieldInt(...string) (int, error)
+	FieldStringList(...string) ([]string, error)
+}) (policy *temporal.RetryPolicy, err error) {
+	var p temporal.RetryPolicy
+	if conf.Contains("retry_policy", "backoff_coefficient") {
+		if p.BackoffCoefficient, err = conf.FieldFloat("retry_policy", "backoff_coefficient"); err != nil {
+			return nil, err
+		}
+		if p.BackoffCoefficient < 1 {
+			return nil, errors.New("retry_policy.backoff_coefficient must be greater than or equal to 1")
+		}
+	}
+	if conf.Contains("retry_policy", "initial_interval") {
+		if p.InitialInterval, err = conf.FieldDuration("retry_policy", "initial_interval"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("retry_policy", "maximum_attempts") {
+		maximumAttempts, err := conf.FieldInt("retry_policy", "maximum_attempts")
+		if err != nil {
+			return nil, err
+		}
+		if maximumAttempts < 0 {
+			return nil, errors.New("retry_policy.maximum_attempts must be greater than or equal to 0")
+		}
+		p.MaximumAttempts = int32(maximumAttempts)
+	}
+	if conf.Contains("retry_policy", "maximum_interval") {
+		if p.MaximumInterval, err = conf.FieldDuration("retry_policy", "maximum_interval"); err != nil {
+			return nil, err
+		}
+		if p.MaximumInterval < p.InitialInterval {
+			return nil, errors.New("retry_policy.maximum_interval must be greater than or equal to retry_policy.initial_interval")
+		}
+	}
+	if conf.Contains("retry_policy", "non_retryable_error_types") {
+		if p.NonRetryableErrorTypes, err = conf.FieldStringList("retry_policy", "non_retryable_error_types"); err != nil {
+			return nil, err
+		}
+	}
+	// optional object and list fields are always present once parsed, so
+	// only apply a retry policy when at least one value was configured
+	if p.BackoffCoefficient == 0 && p.InitialInterval == 0 && p.MaximumAttempts == 0 && p.MaximumInterval == 0 && len(p.NonRetryableErrorTypes) == 0 {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// queryObject evaluates a mapping against msg, expecting an object result.
+// A mapping that deletes the root results in a nil map.
+func queryObject[
+	Mapping BloblangMapping,
+	Message interface {
+		AsStructured() (any, error)
+		BloblangQuery(Mapping) (Message, error)
+	},
+](msg Message, mapping Mapping, name string) (map[string]any, error) {
+	res, err := msg.BloblangQuery(mapping)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating %s: %w", name, err)
+	}
+	var empty Message
+	if reflect.DeepEqual(res, empty) {
+		return nil, nil
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating %s as structured: %w", name, err)
+	}
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected %s to return an object, got: %T", name, v)
+	}
+	return obj, nil
+}
+
+// parseDuration evaluates an interpolated duration field, where an empty
+// result indicates the field should be left unset.
+func parseDuration[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Message any,
+](msg Message, field InterpolatedString, name string) (time.Duration, error) {
+	s, err := field.TryString(msg)
+	if err != nil {
+		return 0, fmt.Errorf("error evaluating %s: %w", name, err)
+	}
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing %s: %w", name, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative", name)
+	}
+	return d, nil
+}
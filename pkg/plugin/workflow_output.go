@@ -2,18 +2,13 @@ package plugin
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"errors"
 	"fmt"
-	"net/http"
-	"os"
-	"reflect"
+	"sync"
+	"time"
 
-	"github.com/cludden/protoc-gen-go-temporal/pkg/scheme"
-	"go.temporal.io/sdk/client"
-	"go.temporal.io/sdk/converter"
-	"google.golang.org/protobuf/encoding/protojson"
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/api/serviceerror"
 )
 
 const (
@@ -30,22 +25,27 @@ type (
 			AsBytes() ([]byte, error)
 			AsStructured() (any, error)
 			BloblangQuery(Mapping) (Message, error)
+			Context() context.Context
+			Copy() Message
+			MetaGet(string) (string, bool)
+			MetaWalk(func(string, string) error) error
+			SetStructured(any)
 		},
+		MessageBatch ~[]Message,
 	] struct {
-		client                 client.Client
-		clientOpts             client.Options
-		dc                     converter.DataConverter
-		detach                 InterpolatedString
-		mapping                Mapping
-		mappingExists          bool
-		inputMessageType       InterpolatedString
-		inputMessageTypeExists bool
-		scheme                 *scheme.Scheme
-		searchAttributes       Mapping
-		searchAttributesExists bool
-		taskQueue              InterpolatedString
-		workflowID             InterpolatedString
-		workflowType           InterpolatedString
+		workflowExecutor[InterpolatedString, Mapping, Message]
+		aggregate         bool
+		alreadyStarted    interface{ Incr(int64, ...string) }
+		batchMaxInFlight  int
+		completionLatency interface{ Timing(int64, ...string) }
+		detach            InterpolatedString
+		failed            interface{ Incr(int64, ...string) }
+		newBatchError     func(MessageBatch, []error) error
+		onAlreadyStarted  string
+		onWorkflowFailure string
+		startErrors       interface{ Incr(int64, ...string) }
+		startLatency      interface{ Timing(int64, ...string) }
+		started           interface{ Incr(int64, ...string) }
 	}
 
 	WorkflowOutputOptions[
@@ -57,8 +57,14 @@ type (
 			AsBytes() ([]byte, error)
 			AsStructured() (any, error)
 			BloblangQuery(Mapping) (Message, error)
+			Context() context.Context
+			Copy() Message
+			MetaGet(string) (string, bool)
+			MetaWalk(func(string, string) error) error
+			SetStructured(any)
 		},
-	] func(*WorkflowOutput[InterpolatedString, Mapping, Message]) error
+		MessageBatch ~[]Message,
+	] func(*WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch]) error
 )
 
 func NewWorkflowOutputConfig[
@@ -72,77 +78,44 @@ func NewWorkflowOutputConfig[
 		Fields(...Field) ConfigSpec
 	},
 	FieldProvider interface {
+		NewBatchPolicyField(string) Field
 		NewBoolField(string) Field
 		NewBloblangField(string) Field
+		NewDurationField(string) Field
+		NewFloatField(string) Field
 		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
 		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
 		NewInterpolatedStringEnumField(string, ...string) Field
 		NewInterpolatedStringField(string) Field
 		NewObjectField(string, ...Field) Field
 	},
 ](conf ConfigSpec, fields FieldProvider) ConfigSpec {
 	return conf.Summary("Executes a Temporal workflow for each message as input.").
+		Fields(newWorkflowExecutorConfigFields[Field](fields)...).
 		Fields(
-			fields.NewStringField("address").
-				Description("Temporal cluster address"),
-			fields.NewStringField("codec_auth").
-				Description("Authorization header for requests to Codec Server").
-				Optional(),
-			fields.NewStringField("codec_endpoint").
-				Description("Endpoint for remote Codec Server").
-				Optional(),
+			fields.NewBoolField("aggregate").
+				Description("Executes a single workflow per batch, where the contents of each message in the batch are aggregated into an array that all mappings and interpolations are evaluated against, using the metadata of the first message").
+				Default(false),
+			fields.NewIntField("batch_max_in_flight").
+				Description("Maximum number of workflows started concurrently for the messages in a batch").
+				Default(64),
+			fields.NewBatchPolicyField("batching").
+				Description("Batching policy, where the workflows for each message in a batch are started concurrently up to batch_max_in_flight"),
 			fields.NewInterpolatedStringEnumField("detach", "true", "false").
 				Description("Starts the workflow execution without waiting for the result").
 				Default("false"),
-			fields.NewInterpolatedStringField("input_proto_message_name").
-				Description("Full name of input proto message").
-				Optional(),
-			fields.NewBloblangField("mapping").
-				Description("Input mapping").
-				Optional(),
 			fields.NewIntField("max_in_flight").
-				Description("Maximum number of pending workflow executions").
+				Description("Maximum number of pending batches, where each batch starts up to batch_max_in_flight workflows concurrently").
 				Default(1),
-			fields.NewStringField("namespace").
-				Description("Temporal namespace name").
-				Default("default"),
-			fields.NewBloblangField("search_attributes").
-				Description("Search attributes mapping").
-				Optional(),
-			fields.NewInterpolatedStringField("task_queue").
-				Description("Worker task queue name"),
-			fields.NewObjectField("tls",
-				fields.NewStringField("ca_file").
-					Description("Path to ca file").
-					Optional(),
-				fields.NewStringField("ca_data").
-					Description("PEM-encoded ca data").
-					Optional(),
-				fields.NewStringField("cert_file").
-					Description("Path to certificate file").
-					Optional(),
-				fields.NewStringField("cert_data").
-					Description("PEM-encoded certificate data").
-					Optional(),
-				fields.NewBoolField("disable_host_verification").
-					Description("Disable TLS host verification").
-					Optional(),
-				fields.NewStringField("key_file").
-					Description("Path to private key").
-					Optional(),
-				fields.NewStringField("key_data").
-					Description("PEM-encoded private key data").
-					Optional(),
-				fields.NewStringField("server_name").
-					Description("Override target TLS server name").
-					Optional(),
-			).
-				Description("Optional TLS configuration").
-				Optional(),
-			fields.NewInterpolatedStringField("workflow_id").
-				Description("Workflow ID"),
-			fields.NewInterpolatedStringField("workflow_type").
-				Description("Workflow type name"),
+			fields.NewStringEnumField("on_already_started", "error", "ack", "attach_and_wait").
+				Description("Behavior when a workflow with the same ID is already running, where attach_and_wait waits for the existing run unless detached").
+				Default("attach_and_wait"),
+			fields.NewStringEnumField("on_workflow_failure", "ack", "nack", "error").
+				Description("Behavior when a workflow fails, times out, or is cancelled or terminated, where error rejects the message with an error describing the failure as a JSON object (e.g. to a fallback output), nack is an alias of error accepted for consistency with the temporal_workflow processor, and ack acknowledges it").
+				Default("error"),
 		)
 }
 
@@ -155,238 +128,216 @@ func NewWorkflowOutput[
 		AsBytes() ([]byte, error)
 		AsStructured() (any, error)
 		BloblangQuery(Mapping) (Message, error)
+		Context() context.Context
+		Copy() Message
+		MetaGet(string) (string, bool)
+		MetaWalk(func(string, string) error) error
+		SetStructured(any)
 	},
+	MessageBatch ~[]Message,
+	BatchPolicy any,
 	ParsedConfig interface {
 		Contains(...string) bool
+		FieldBatchPolicy(...string) (BatchPolicy, error)
 		FieldBloblang(...string) (Mapping, error)
 		FieldBool(...string) (bool, error)
+		FieldDuration(...string) (time.Duration, error)
+		FieldFloat(...string) (float64, error)
 		FieldInt(...string) (int, error)
 		FieldInterpolatedString(...string) (InterpolatedString, error)
 		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
 	},
-	Resources any,
-](conf ParsedConfig, mgr Resources, opts ...WorkflowOutputOptions[InterpolatedString, Mapping, Message]) (o *WorkflowOutput[InterpolatedString, Mapping, Message], maxInFlight int, err error) {
-	o = &WorkflowOutput[InterpolatedString, Mapping, Message]{}
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+		OtelTracer() trace.TracerProvider
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, newBatchError func(MessageBatch, []error) error, opts ...WorkflowOutputOptions[InterpolatedString, Mapping, Message, MessageBatch]) (o *WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch], batchPolicy BatchPolicy, maxInFlight int, err error) {
+	metrics := mgr.Metrics()
+	o = &WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch]{
+		alreadyStarted:    metrics.NewCounter("temporal_workflow_already_started", "workflow_type", "task_queue"),
+		completionLatency: metrics.NewTimer("temporal_workflow_completion_latency", "workflow_type", "task_queue"),
+		failed:            metrics.NewCounter("temporal_workflow_failed", "workflow_type", "task_queue"),
+		newBatchError:     newBatchError,
+		startErrors:       metrics.NewCounter("temporal_workflow_start_error", "workflow_type", "task_queue"),
+		startLatency:      metrics.NewTimer("temporal_workflow_start_latency", "workflow_type", "task_queue"),
+		started:           metrics.NewCounter("temporal_workflow_started", "workflow_type", "task_queue"),
+	}
+	o.errorWhenStarted = true
 	for _, opt := range opts {
 		if err := opt(o); err != nil {
-			return nil, 0, err
+			return nil, batchPolicy, 0, err
 		}
 	}
-	if o.clientOpts.HostPort, err = conf.FieldString("address"); err != nil {
-		return nil, 0, err
+	setClientTelemetry(&o.clientOpts, mgr)
+	if err := o.parse(conf); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if o.dc == nil {
-		o.dc = converter.GetDefaultDataConverter()
+	if err := initClientConn(&o.clientConn, conf, mgr); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if conf.Contains("codec_endpoint") {
-		var codecOpts converter.RemotePayloadCodecOptions
-		if codecOpts.Endpoint, err = conf.FieldString("codec_endpoint"); err != nil {
-			return nil, 0, err
-		}
-		if conf.Contains("codec_auth") {
-			codecAuth, err := conf.FieldString("codec_auth")
-			if err != nil {
-				return nil, 0, err
-			}
-			codecOpts.ModifyRequest = func(r *http.Request) error {
-				r.Header.Set("Authorization", codecAuth)
-				return nil
-			}
-		}
-		o.dc = converter.NewCodecDataConverter(o.dc, converter.NewRemotePayloadCodec(codecOpts))
+	if err := o.setTracerProvider(mgr.OtelTracer()); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if o.detach, err = conf.FieldInterpolatedString("detach"); err != nil {
-		return nil, 0, err
-	}
-	if conf.Contains("input_proto_message_name") {
-		o.inputMessageTypeExists = true
-		if o.inputMessageType, err = conf.FieldInterpolatedString("input_proto_message_name"); err != nil {
-			return nil, 0, err
-		}
+	if o.aggregate, err = conf.FieldBool("aggregate"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if conf.Contains("mapping") {
-		o.mappingExists = true
-		if o.mapping, err = conf.FieldBloblang("mapping"); err != nil {
-			return nil, 0, err
-		}
+	if o.batchMaxInFlight, err = conf.FieldInt("batch_max_in_flight"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
-		return nil, 0, err
+	if o.batchMaxInFlight < 1 {
+		return nil, batchPolicy, 0, errors.New("batch_max_in_flight must be at least 1")
 	}
-	if o.clientOpts.Namespace, err = conf.FieldString("namespace"); err != nil {
-		return nil, 0, err
+	if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if conf.Contains("search_attributes") {
-		o.searchAttributesExists = true
-		if o.searchAttributes, err = conf.FieldBloblang("search_attributes"); err != nil {
-			return nil, 0, err
-		}
-	}
-	if o.taskQueue, err = conf.FieldInterpolatedString("task_queue"); err != nil {
-		return nil, 0, err
+	if o.detach, err = conf.FieldInterpolatedString("detach"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if o.clientOpts.ConnectionOptions.TLS, err = parseTLS[InterpolatedString, Mapping, Message, ParsedConfig](conf); err != nil {
-		return nil, 0, err
+	if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if o.workflowID, err = conf.FieldInterpolatedString("workflow_id"); err != nil {
-		return nil, 0, err
+	if o.onAlreadyStarted, err = conf.FieldString("on_already_started"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	if o.workflowType, err = conf.FieldInterpolatedString("workflow_type"); err != nil {
-		return nil, 0, err
+	if o.onWorkflowFailure, err = conf.FieldString("on_workflow_failure"); err != nil {
+		return nil, batchPolicy, 0, err
 	}
-	return o, maxInFlight, nil
+	return o, batchPolicy, maxInFlight, nil
 }
 
-func (o *WorkflowOutput[InterpolatedString, Mapping, Message]) Close(ctx context.Context) error {
-	o.client.Close()
-	return nil
-}
-
-func (o *WorkflowOutput[InterpolatedString, Mapping, Message]) Connect(ctx context.Context) (err error) {
-	if o.client, err = client.Dial(o.clientOpts); err != nil {
-		return fmt.Errorf("error connecting to Temporal: %w", err)
-	}
-	return nil
-}
-
-func (o *WorkflowOutput[InterpolatedString, Mapping, Message]) Write(ctx context.Context, msg Message) (err error) {
-	var opts client.StartWorkflowOptions
-	if opts.ID, err = o.workflowID.TryString(msg); err != nil {
-		return fmt.Errorf("error evaluating workflow_id: %w", err)
-	}
-	if opts.TaskQueue, err = o.taskQueue.TryString(msg); err != nil {
-		return fmt.Errorf("error evaluating task_queue: %w", err)
-	}
-	workflowType, err := o.workflowType.TryString(msg)
-	if err != nil {
-		return fmt.Errorf("error evaluating workflow_type: %w", err)
-	}
-	if o.mappingExists {
-		if msg, err = msg.BloblangQuery(o.mapping); err != nil {
-			return fmt.Errorf("error applying output mapping: %w", err)
-		}
-	}
-	if o.searchAttributesExists {
-		searchAttributes, err := o.searchAttributes.Query(msg)
-		if err != nil {
-			return fmt.Errorf("error evaluating search_attributes: %w", err)
-		}
-		sa, ok := searchAttributes.(map[string]any)
-		if !ok {
-			return fmt.Errorf("expected search_attributes to return an object, got: %T", searchAttributes)
-		}
-		opts.SearchAttributes = sa
+// WriteBatch executes a workflow for each message in the batch concurrently,
+// up to batchMaxInFlight at a time, reporting the individual messages that
+// failed, or a single workflow for the entire batch when aggregate is enabled.
+func (o *WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch]) WriteBatch(ctx context.Context, batch MessageBatch) error {
+	if len(batch) == 0 {
+		return nil
 	}
-
-	var run client.WorkflowRun
-	var empty Message
-	if !reflect.DeepEqual(msg, empty) {
-		var arg any
-		if o.scheme != nil && o.inputMessageTypeExists {
-			messageType, err := o.inputMessageType.TryString(msg)
+	if o.aggregate {
+		items := make([]any, len(batch))
+		for i, msg := range batch {
+			item, err := msg.AsStructured()
 			if err != nil {
-				return fmt.Errorf("error evaluating input_proto_message_name: %w", err)
+				return fmt.Errorf("error evaluating message %d as structured: %w", i, err)
 			}
-			pb, err := o.scheme.New(messageType)
-			if err != nil {
-				return fmt.Errorf("error initializing new %s value: %w", messageType, err)
-			}
-			b, err := msg.AsBytes()
-			if err != nil {
-				return fmt.Errorf("error serializing message bytes: %w", err)
-			}
-			if err = protojson.Unmarshal(b, pb); err != nil {
-				return fmt.Errorf("error unmarshalling message proto: %w", err)
+			items[i] = item
+		}
+		msg := batch[0].Copy()
+		msg.SetStructured(items)
+		if err := o.write(ctx, msg); err != nil {
+			// the workflow was executed on behalf of every message in the batch
+			errs := make([]error, len(batch))
+			for i := range errs {
+				errs[i] = err
 			}
-			arg = pb
-		} else if arg, err = msg.AsStructured(); err != nil {
-			return fmt.Errorf("error evaluating message as structured: %w", err)
+			return o.newBatchError(batch, errs)
 		}
-
-		run, err = o.client.ExecuteWorkflow(ctx, opts, workflowType, arg)
-	} else {
-		run, err = o.client.ExecuteWorkflow(ctx, opts, workflowType)
-	}
-	if err != nil {
-		return fmt.Errorf("error executing workflow: %w", err)
-	}
-	if detach, _ := o.detach.TryString(msg); detach == "true" {
 		return nil
 	}
-	return run.Get(ctx, nil)
-}
-
-func parseTLS[
-	InterpolatedString interface {
-		TryString(Message) (string, error)
-	},
-	Mapping BloblangMapping,
-	Message interface {
-		AsBytes() ([]byte, error)
-		AsStructured() (any, error)
-		BloblangQuery(Mapping) (Message, error)
-	},
-	ParsedConfig interface {
-		Contains(...string) bool
-		FieldBloblang(...string) (Mapping, error)
-		FieldBool(...string) (bool, error)
-		FieldInt(...string) (int, error)
-		FieldInterpolatedString(...string) (InterpolatedString, error)
-		FieldString(...string) (string, error)
-	},
-](conf ParsedConfig) (cfg *tls.Config, err error) {
-	cfg = &tls.Config{}
-
-	var caBytes []byte
-	if caFile, _ := conf.FieldString("tls", "ca_file"); caFile != "" {
-		if conf.Contains("tls", "ca_data") {
-			return nil, errors.New("cannot specify both ca_data and ca_file")
-		}
-		if caBytes, err = os.ReadFile(caFile); err != nil {
-			return nil, err
-		}
-	} else if caData, _ := conf.FieldString("tls", "ca_data"); caData != "" {
-		caBytes = []byte(caData)
-	}
-	if len(caBytes) > 0 {
-		cfg.RootCAs = x509.NewCertPool()
-		if !cfg.RootCAs.AppendCertsFromPEM(caBytes) {
-			return nil, errors.New("invalid CA cert data")
+	if len(batch) == 1 {
+		return o.write(ctx, batch[0])
+	}
+	errs := make([]error, len(batch))
+	sem := make(chan struct{}, o.batchMaxInFlight)
+	var wg sync.WaitGroup
+	for i, msg := range batch {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, msg Message) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = o.write(ctx, msg)
+		}(i, msg)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return o.newBatchError(batch, errs)
 		}
 	}
+	return nil
+}
 
-	var clientCert tls.Certificate
-	var hasClientCert bool
-	if conf.Contains("tls", "cert_file") && conf.Contains("tls", "key_file") {
-		certFile, _ := conf.FieldString("tls", "cert_file")
-		keyFile, _ := conf.FieldString("tls", "key_file")
-		clientCert, err = tls.LoadX509KeyPair(certFile, keyFile)
-		hasClientCert = true
-	} else if conf.Contains("tls", "cert_data") && conf.Contains("tls", "key_data") {
-		certData, _ := conf.FieldString("tls", "cert_data")
-		keyData, _ := conf.FieldString("tls", "key_data")
-		clientCert, err = tls.X509KeyPair([]byte(certData), []byte(keyData))
-		hasClientCert = true
+func (o *WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch]) write(ctx context.Context, msg Message) (err error) {
+	workflowType, taskQueue := o.metricLabels(msg)
+	c, release, err := o.acquireClient(ctx, msg)
+	if err != nil {
+		o.startErrors.Incr(1, workflowType, taskQueue)
+		return err
 	}
+	defer func() { release(err) }()
+	start := time.Now()
+	run, err := o.execute(ctx, c, msg)
 	if err != nil {
-		return nil, fmt.Errorf("error loading client certificate: %w", err)
+		var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+		if !errors.As(err, &alreadyStarted) {
+			o.startErrors.Incr(1, workflowType, taskQueue)
+			return err
+		}
+		o.alreadyStarted.Incr(1, workflowType, taskQueue)
+		switch o.onAlreadyStarted {
+		case "ack":
+			return nil
+		case "error":
+			return err
+		}
+		// attach_and_wait continues with the existing run
+	} else {
+		o.started.Incr(1, workflowType, taskQueue)
+		o.startLatency.Timing(time.Since(start).Nanoseconds(), workflowType, taskQueue)
 	}
-	if hasClientCert {
-		cfg.Certificates = append(cfg.Certificates, clientCert)
-		hasClientCert = true
+	if detach, _ := o.detach.TryString(msg); detach == "true" {
+		return nil
 	}
-	if conf.Contains("tls", "disable_host_verification") {
-		if cfg.InsecureSkipVerify, err = conf.FieldBool("tls", "disable_host_verification"); err != nil {
-			return nil, err
+	if err := run.Get(ctx, nil); err != nil {
+		failure, ok := newWorkflowFailure(err)
+		if !ok {
+			return fmt.Errorf("error getting workflow result: %w", err)
 		}
-	}
-	if conf.Contains("tls", "server_name") {
-		if cfg.ServerName, err = conf.FieldString("tls", "server_name"); err != nil {
-			return nil, err
+		o.completionLatency.Timing(time.Since(start).Nanoseconds(), workflowType, taskQueue)
+		o.failed.Incr(1, workflowType, taskQueue)
+		if o.onWorkflowFailure == "ack" {
+			return nil
 		}
+		return &workflowFailureError{err: err, failure: failure, runID: run.GetRunID(), workflowID: run.GetID()}
 	}
-	if len(cfg.Certificates) > 0 || cfg.InsecureSkipVerify || cfg.RootCAs != nil || cfg.ServerName != "" {
-		return cfg, nil
-	}
-	return nil, nil
+	o.completionLatency.Timing(time.Since(start).Nanoseconds(), workflowType, taskQueue)
+	return nil
+}
+
+// metricLabels returns the workflow_type and task_queue label values for msg,
+// which are empty if they cannot be evaluated.
+func (o *WorkflowOutput[InterpolatedString, Mapping, Message, MessageBatch]) metricLabels(msg Message) (workflowType, taskQueue string) {
+	workflowType, _ = o.workflowType.TryString(msg)
+	taskQueue, _ = o.taskQueue.TryString(msg)
+	return workflowType, taskQueue
 }
@@ -0,0 +1,283 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.temporal.io/sdk/client"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	WorkflowProcessorType = "temporal_workflow"
+)
+
+type (
+	WorkflowProcessor[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangMutateFrom(Mapping, Message) (Message, error)
+			BloblangQuery(Mapping) (Message, error)
+			Context() context.Context
+			Copy() Message
+			MetaGet(string) (string, bool)
+			MetaSet(string, string)
+			MetaWalk(func(string, string) error) error
+			SetBytes([]byte)
+			SetError(error)
+			SetStructured(any)
+		},
+		MessageBatch any,
+	] struct {
+		workflowExecutor[InterpolatedString, Mapping, Message]
+		onWorkflowFailure       string
+		outputMessageType       InterpolatedString
+		outputMessageTypeExists bool
+		resultMapping           Mapping
+		resultMappingExists     bool
+		toBatch                 func([]Message) MessageBatch
+	}
+
+	WorkflowProcessorOptions[
+		InterpolatedString interface {
+			TryString(Message) (string, error)
+		},
+		Mapping BloblangMapping,
+		Message interface {
+			AsBytes() ([]byte, error)
+			AsStructured() (any, error)
+			BloblangMutateFrom(Mapping, Message) (Message, error)
+			BloblangQuery(Mapping) (Message, error)
+			Context() context.Context
+			Copy() Message
+			MetaGet(string) (string, bool)
+			MetaSet(string, string)
+			MetaWalk(func(string, string) error) error
+			SetBytes([]byte)
+			SetError(error)
+			SetStructured(any)
+		},
+		MessageBatch any,
+	] func(*WorkflowProcessor[InterpolatedString, Mapping, Message, MessageBatch]) error
+)
+
+func NewWorkflowProcessorConfig[
+	Field interface {
+		Default(any) Field
+		Description(string) Field
+		Optional() Field
+	},
+	ConfigSpec interface {
+		Summary(string) ConfigSpec
+		Fields(...Field) ConfigSpec
+	},
+	FieldProvider interface {
+		NewBoolField(string) Field
+		NewBloblangField(string) Field
+		NewDurationField(string) Field
+		NewFloatField(string) Field
+		NewIntField(string) Field
+		NewStringEnumField(string, ...string) Field
+		NewStringField(string) Field
+		NewStringListField(string) Field
+		NewStringMapField(string) Field
+		NewInterpolatedStringEnumField(string, ...string) Field
+		NewInterpolatedStringField(string) Field
+		NewObjectField(string, ...Field) Field
+	},
+](conf ConfigSpec, fields FieldProvider) ConfigSpec {
+	return conf.Summary("Executes a Temporal workflow for each message and replaces the message with the workflow result.").
+		Fields(newWorkflowExecutorConfigFields[Field](fields)...).
+		Fields(
+			fields.NewStringEnumField("on_workflow_failure", "ack", "nack", "error").
+				Description("Behavior when a workflow fails, times out, or is cancelled or terminated, where ack and error emit the original message with failure metadata (error also flags the message as errored) and nack fails processing").
+				Default("error"),
+			fields.NewInterpolatedStringField("output_proto_message_name").
+				Description("Full name of output proto message").
+				Optional(),
+			fields.NewBloblangField("result_mapping").
+				Description("Maps the workflow result onto the original message, where root is the original message and this is the workflow result").
+				Optional(),
+		)
+}
+
+func NewWorkflowProcessor[
+	InterpolatedString interface {
+		TryString(Message) (string, error)
+	},
+	Mapping BloblangMapping,
+	Message interface {
+		AsBytes() ([]byte, error)
+		AsStructured() (any, error)
+		BloblangMutateFrom(Mapping, Message) (Message, error)
+		BloblangQuery(Mapping) (Message, error)
+		Context() context.Context
+		Copy() Message
+		MetaGet(string) (string, bool)
+		MetaSet(string, string)
+		MetaWalk(func(string, string) error) error
+		SetBytes([]byte)
+		SetError(error)
+		SetStructured(any)
+	},
+	MessageBatch any,
+	ParsedConfig interface {
+		Contains(...string) bool
+		FieldBloblang(...string) (Mapping, error)
+		FieldBool(...string) (bool, error)
+		FieldDuration(...string) (time.Duration, error)
+		FieldFloat(...string) (float64, error)
+		FieldInt(...string) (int, error)
+		FieldInterpolatedString(...string) (InterpolatedString, error)
+		FieldString(...string) (string, error)
+		FieldStringList(...string) ([]string, error)
+		FieldStringMap(...string) (map[string]string, error)
+	},
+	Resources interface {
+		AccessCache(context.Context, string, func(Cache)) error
+		Logger() Logger
+		Metrics() Metrics
+		OtelTracer() trace.TracerProvider
+	},
+	Cache interface {
+		Get(context.Context, string) ([]byte, error)
+	},
+	Logger interface {
+		Debug(string)
+		Error(string)
+		Info(string)
+		Warn(string)
+		With(...any) Logger
+	},
+	Metrics interface {
+		NewCounter(string, ...string) Counter
+		NewGauge(string, ...string) Gauge
+		NewTimer(string, ...string) Timer
+	},
+	Counter interface {
+		Incr(int64, ...string)
+	},
+	Gauge interface {
+		Set(int64, ...string)
+	},
+	Timer interface {
+		Timing(int64, ...string)
+	},
+](conf ParsedConfig, mgr Resources, toBatch func([]Message) MessageBatch, opts ...WorkflowProcessorOptions[InterpolatedString, Mapping, Message, MessageBatch]) (p *WorkflowProcessor[InterpolatedString, Mapping, Message, MessageBatch], err error) {
+	p = &WorkflowProcessor[InterpolatedString, Mapping, Message, MessageBatch]{
+		toBatch: toBatch,
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	setClientTelemetry(&p.clientOpts, mgr)
+	if err := p.parse(conf); err != nil {
+		return nil, err
+	}
+	if err := initClientConn(&p.clientConn, conf, mgr); err != nil {
+		return nil, err
+	}
+	if err := p.setTracerProvider(mgr.OtelTracer()); err != nil {
+		return nil, err
+	}
+	if p.onWorkflowFailure, err = conf.FieldString("on_workflow_failure"); err != nil {
+		return nil, err
+	}
+	if conf.Contains("output_proto_message_name") {
+		p.outputMessageTypeExists = true
+		if p.outputMessageType, err = conf.FieldInterpolatedString("output_proto_message_name"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("result_mapping") {
+		p.resultMappingExists = true
+		if p.resultMapping, err = conf.FieldBloblang("result_mapping"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.connectLazy(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *WorkflowProcessor[InterpolatedString, Mapping, Message, MessageBatch]) Process(ctx context.Context, msg Message) (result MessageBatch, err error) {
+	if err := p.ensureConnected(ctx); err != nil {
+		return result, err
+	}
+	c, release, err := p.acquireClient(ctx, msg)
+	if err != nil {
+		return result, err
+	}
+	defer func() { release(err) }()
+	run, err := p.execute(ctx, c, msg)
+	if err != nil {
+		return result, err
+	}
+
+	res := msg.Copy()
+	if p.scheme != nil && p.outputMessageTypeExists {
+		messageType, err := p.outputMessageType.TryString(msg)
+		if err != nil {
+			return result, fmt.Errorf("error evaluating output_proto_message_name: %w", err)
+		}
+		pb, err := p.scheme.New(messageType)
+		if err != nil {
+			return result, fmt.Errorf("error initializing new %s value: %w", messageType, err)
+		}
+		if err := run.Get(ctx, pb); err != nil {
+			return p.fail(msg, run, err)
+		}
+		b, err := protojson.Marshal(pb)
+		if err != nil {
+			return result, fmt.Errorf("error marshalling result proto: %w", err)
+		}
+		res.SetBytes(b)
+	} else {
+		var v any
+		if err := run.Get(ctx, &v); err != nil {
+			return p.fail(msg, run, err)
+		}
+		res.SetStructured(v)
+	}
+
+	if p.resultMappingExists {
+		if res, err = msg.Copy().BloblangMutateFrom(p.resultMapping, res); err != nil {
+			return result, fmt.Errorf("error applying result_mapping: %w", err)
+		}
+		var empty Message
+		if reflect.DeepEqual(res, empty) {
+			return p.toBatch(nil), nil
+		}
+	}
+	res.MetaSet("workflow_id", run.GetID())
+	res.MetaSet("run_id", run.GetRunID())
+	return p.toBatch([]Message{res}), nil
+}
+
+// fail handles an error getting the workflow result, emitting the original
+// message with failure metadata when the workflow itself failed.
+func (p *WorkflowProcessor[InterpolatedString, Mapping, Message, MessageBatch]) fail(msg Message, run client.WorkflowRun, err error) (result MessageBatch, _ error) {
+	failure, ok := newWorkflowFailure(err)
+	if !ok || p.onWorkflowFailure == "nack" {
+		return result, fmt.Errorf("error getting workflow result: %w", err)
+	}
+	res := msg.Copy()
+	res.MetaSet("workflow_id", run.GetID())
+	res.MetaSet("run_id", run.GetRunID())
+	failure.setMetadata(res)
+	if p.onWorkflowFailure == "error" {
+		res.SetError(fmt.Errorf("workflow %s failed: %w", run.GetID(), err))
+	}
+	return p.toBatch([]Message{res}), nil
+}